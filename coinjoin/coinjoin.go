@@ -0,0 +1,217 @@
+// Package coinjoin - assembles transaction spending inputs of all peers
+// into outputs mixed via DiceMix
+package coinjoin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/txsort"
+)
+
+// MaxFee - most satoshis every peer may pay as fee
+// transaction has no change outputs, so inputs of peer
+// exceeding its outputs are paid as fee entirely
+const MaxFee = 10000
+
+// size of P2WPKH script, smallest output allowed
+const p2wpkhSize = 22
+
+// Outputs - decodes output scripts from slots resolved in DC-SIMPLE
+// every message should be P2PKH or P2WPKH script
+func Outputs(allMessages [][]byte) ([][]byte, error) {
+	scripts, err := dc.Unpack(allMessages)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrProtocolViolation, "%v", err)
+	}
+
+	for i, script := range scripts {
+		if err := CheckScript(script); err != nil {
+			return nil, utils.NewError(utils.ErrProtocolViolation, "output %d: %v", i, err)
+		}
+	}
+	return scripts, nil
+}
+
+// CheckScript - checks if outputs can pay to script
+func CheckScript(script []byte) error {
+	switch txscript.GetScriptClass(script) {
+	case txscript.PubKeyHashTy, txscript.WitnessV0PubKeyHashTy:
+		return nil
+	}
+	return fmt.Errorf("script is not P2PKH or P2WPKH")
+}
+
+// MaxOutputs - most outputs peer occupying slots of slotSize can receive
+// every output occupies at least as many slots as P2WPKH script
+func MaxOutputs(slots, slotSize uint32) uint32 {
+	chunks, err := dc.Pack(make([]byte, p2wpkhSize), slotSize)
+	if err != nil {
+		return slots
+	}
+	return slots / uint32(len(chunks))
+}
+
+// CheckFunds - checks if inputs of peer occupying slots pay for
+// every output it can receive, and surplus paid as fee does'nt exceed MaxFee
+// so that no peer receives outputs paid by inputs of others
+func CheckFunds(inputs []utils.Input, slots, slotSize uint32, denomination uint64) error {
+	if denomination == 0 || denomination > btcutil.MaxSatoshi {
+		return fmt.Errorf("invalid denomination %d", denomination)
+	}
+
+	var in uint64
+	for i, input := range inputs {
+		if input.Value > btcutil.MaxSatoshi {
+			return fmt.Errorf("input %d of %d satoshis", i, input.Value)
+		}
+		in += input.Value
+	}
+
+	outputs := MaxOutputs(slots, slotSize)
+	out := uint64(outputs) * denomination
+	if out/denomination != uint64(outputs) || in < out {
+		return fmt.Errorf("inputs of %d satoshis does'nt cover %d outputs of %d", in, outputs, denomination)
+	}
+	if in-out > MaxFee {
+		return fmt.Errorf("inputs of %d satoshis would pay %d as fee, at most %d allowed", in, in-out, MaxFee)
+	}
+	return nil
+}
+
+// Build - assembles unsigned transaction spending inputs
+// into outputs of denomination paying to scripts
+// inputs and outputs are ordered as in BIP-69, so same inputs and scripts
+// yield same transaction regardless of their order
+// difference of inputs and outputs is paid as fee, CheckFunds bounds share of every peer
+func Build(inputs []utils.Input, scripts [][]byte, denomination uint64) (*wire.MsgTx, error) {
+	if len(inputs) == 0 || len(scripts) == 0 {
+		return nil, fmt.Errorf("transaction with %d inputs and %d outputs", len(inputs), len(scripts))
+	}
+	if denomination == 0 || denomination > btcutil.MaxSatoshi {
+		return nil, fmt.Errorf("invalid denomination %d", denomination)
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+
+	var in uint64
+	for i, input := range inputs {
+		hash, err := chainhash.NewHash(input.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %v", i, err)
+		}
+		if input.Value > btcutil.MaxSatoshi {
+			return nil, fmt.Errorf("input %d of %d satoshis", i, input.Value)
+		}
+
+		in += input.Value
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, input.Index), nil, nil))
+	}
+
+	for _, script := range scripts {
+		tx.AddTxOut(wire.NewTxOut(int64(denomination), script))
+	}
+
+	out := denomination * uint64(len(scripts))
+	if out/uint64(len(scripts)) != denomination || out > in {
+		return nil, fmt.Errorf("inputs of %d satoshis does'nt cover %d outputs of %d", in, len(scripts), denomination)
+	}
+
+	txsort.InPlaceSort(tx)
+	return tx, nil
+}
+
+// FromState - assembles transaction of run
+// from our inputs, inputs announced by peers and messages resolved in DC-SIMPLE
+func FromState(state *utils.State) (*wire.MsgTx, error) {
+	inputs, err := Inputs(state)
+	if err != nil {
+		return nil, err
+	}
+
+	scripts, err := Outputs(state.AllMessages)
+	if err != nil {
+		return nil, err
+	}
+	return Build(inputs, scripts, state.Pool.Denomination)
+}
+
+// Inputs - returns our inputs along with inputs announced by peers
+// every peer should spend distinct outputs
+// amounts announced by peers are taken as is, see VerifyUTXOs
+func Inputs(state *utils.State) ([]utils.Input, error) {
+	// every outpoint should be spent only once
+	spent := make(map[string]int32)
+	inputs := make([]utils.Input, 0, len(state.MyInputs))
+
+	add := func(id int32, own []utils.Input) error {
+		for _, input := range own {
+			key := outpoint(input.TxHash, input.Index)
+			if owner, ok := spent[key]; ok {
+				return utils.NewPeerError(blamed(state.Session.MyID, owner, id), "input spent twice")
+			}
+			spent[key] = id
+		}
+		inputs = append(inputs, own...)
+		return nil
+	}
+
+	if len(state.MyInputs) == 0 {
+		return nil, fmt.Errorf("no inputs to spend")
+	}
+	if err := CheckFunds(state.MyInputs, state.MyMsgCount, state.Pool.SlotSize, state.Pool.Denomination); err != nil {
+		return nil, err
+	}
+	if err := add(state.Session.MyID, state.MyInputs); err != nil {
+		return nil, err
+	}
+
+	// peer without inputs covering its outputs would get them paid by others
+	for _, peer := range state.Peers {
+		if len(peer.Inputs) == 0 {
+			return nil, utils.NewPeerError([]int32{peer.ID}, "no inputs announced")
+		}
+		if err := CheckFunds(peer.Inputs, peer.NumMsgs, state.Pool.SlotSize, state.Pool.Denomination); err != nil {
+			return nil, utils.NewPeerError([]int32{peer.ID}, "%v", err)
+		}
+		if err := add(peer.ID, peer.Inputs); err != nil {
+			return nil, err
+		}
+	}
+	return inputs, nil
+}
+
+// Serialize - encodes transaction in bitcoin wire format
+func Serialize(tx *wire.MsgTx) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := tx.Serialize(&buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// identifies previous output hash:index
+func outpoint(hash []byte, index uint32) string {
+	key := make([]byte, 4)
+	binary.LittleEndian.PutUint32(key, index)
+	return string(append(append([]byte{}, hash...), key...))
+}
+
+// peers spending same outpoint, we are'nt blamed
+func blamed(myID int32, ids ...int32) []int32 {
+	peers := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		if id != myID && (len(peers) == 0 || peers[len(peers)-1] != id) {
+			peers = append(peers, id)
+		}
+	}
+	return peers
+}
@@ -0,0 +1,178 @@
+package coinjoin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+const denomination = 100000
+
+// P2PKH and P2WPKH scripts paying to hash160 filled with b
+func p2pkh(b byte) []byte {
+	script := append([]byte{0x76, 0xa9, 0x14}, bytes.Repeat([]byte{b}, 20)...)
+	return append(script, 0x88, 0xac)
+}
+
+func p2wpkh(b byte) []byte {
+	return append([]byte{0x00, 0x14}, bytes.Repeat([]byte{b}, 20)...)
+}
+
+func input(b byte, index uint32, value uint64) utils.Input {
+	return utils.Input{TxHash: bytes.Repeat([]byte{b}, 32), Index: index, Value: value, PkScript: p2wpkh(b)}
+}
+
+// packs scripts into slots as resolved in DC-SIMPLE
+func slots(t *testing.T, scripts ...[]byte) [][]byte {
+	var all [][]byte
+	for _, script := range scripts {
+		packed, err := dc.Pack(script, dc.DefaultSlotSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, packed...)
+	}
+	return all
+}
+
+func TestBuildDeterministic(t *testing.T) {
+	inputs := []utils.Input{input(3, 0, 60000), input(1, 1, 70000), input(1, 0, 80000)}
+	scripts := [][]byte{p2pkh(2), p2wpkh(1)}
+
+	tx, err := Build(inputs, scripts, denomination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := Serialize(tx)
+
+	// peers derive same transaction from inputs and scripts in any order
+	reversed, err := Build([]utils.Input{inputs[2], inputs[0], inputs[1]}, [][]byte{scripts[1], scripts[0]}, denomination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Serialize(reversed); !bytes.Equal(got, expected) {
+		t.Error("For", "reordered inputs", "expected", expected, "got", got)
+	}
+
+	if len(tx.TxIn) != 3 || len(tx.TxOut) != 2 {
+		t.Fatal("For", "3 inputs and 2 outputs", "expected", "3 inputs and 2 outputs", "got", tx)
+	}
+	if tx.TxIn[0].PreviousOutPoint.Index != 0 || tx.TxIn[1].PreviousOutPoint.Index != 1 || tx.TxIn[2].PreviousOutPoint.Hash[0] != 3 {
+		t.Error("For", "BIP-69", "expected", "sorted inputs", "got", tx.TxIn)
+	}
+	for _, out := range tx.TxOut {
+		if out.Value != denomination {
+			t.Error("For", "output", "expected", denomination, "got", out.Value)
+		}
+	}
+	if !bytes.Equal(tx.TxOut[0].PkScript, scripts[1]) {
+		t.Error("For", "BIP-69", "expected", scripts[1], "got", tx.TxOut[0].PkScript)
+	}
+}
+
+func TestBuildInvalid(t *testing.T) {
+	valid := []utils.Input{input(1, 0, denomination)}
+	scripts := [][]byte{p2pkh(1)}
+
+	tests := []struct {
+		inputs       []utils.Input
+		scripts      [][]byte
+		denomination uint64
+	}{
+		{nil, scripts, denomination},
+		{valid, nil, denomination},
+		{valid, scripts, 0},
+		{valid, [][]byte{p2pkh(1), p2pkh(2)}, denomination},
+		{[]utils.Input{{TxHash: []byte{1}, Value: denomination}}, scripts, denomination},
+	}
+
+	for i, test := range tests {
+		if _, err := Build(test.inputs, test.scripts, test.denomination); err == nil {
+			t.Error("For", i, "expected", "error", "got", nil)
+		}
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	scripts, err := Outputs(slots(t, p2pkh(1), p2wpkh(2)))
+	if err != nil || len(scripts) != 2 || !bytes.Equal(scripts[0], p2pkh(1)) || !bytes.Equal(scripts[1], p2wpkh(2)) {
+		t.Error("For", "P2PKH and P2WPKH", "expected", "scripts", "got", scripts, err)
+	}
+
+	// P2SH can't receive mixed outputs
+	p2sh := append(append([]byte{0xa9, 0x14}, bytes.Repeat([]byte{1}, 20)...), 0x87)
+	if _, err := Outputs(slots(t, p2pkh(1), p2sh)); !errors.Is(err, utils.ErrProtocolViolation) {
+		t.Error("For", "P2SH", "expected", utils.ErrProtocolViolation, "got", err)
+	}
+}
+
+func TestFromState(t *testing.T) {
+	state := &utils.State{
+		Pool:        utils.Pool{Denomination: denomination, SlotSize: dc.DefaultSlotSize},
+		MyMsgCount:  2,
+		MyInputs:    []utils.Input{input(1, 0, denomination)},
+		AllMessages: slots(t, p2pkh(1), p2wpkh(2)),
+		Peers:       []utils.Peers{{ID: 2, NumMsgs: 2, Inputs: []utils.Input{input(2, 0, denomination)}}},
+	}
+	state.Session.MyID = 1
+
+	if _, err := FromState(state); err != nil {
+		t.Error("For", "valid run", "expected", nil, "got", err)
+	}
+
+	tests := []struct {
+		inputs []utils.Input
+		peers  []int32
+	}{
+		// peer without inputs
+		{nil, []int32{2}},
+		// peer spending our input
+		{[]utils.Input{input(1, 0, denomination)}, []int32{2}},
+		// peer spending its input twice
+		{[]utils.Input{input(2, 0, denomination), input(2, 0, denomination)}, []int32{2}},
+		// peer whose output would be paid by our input
+		{[]utils.Input{input(2, 0, 1)}, []int32{2}},
+	}
+
+	for i, test := range tests {
+		state.Peers[0].Inputs = test.inputs
+
+		var e *utils.Error
+		_, err := FromState(state)
+		if !errors.As(err, &e) || !errors.Is(err, utils.ErrPeerMisbehaviour) || len(e.Peers) != 1 || e.Peers[0] != test.peers[0] {
+			t.Error("For", i, "expected", test.peers, "got", err)
+		}
+	}
+}
+
+func TestCheckFunds(t *testing.T) {
+	// P2PKH and P2WPKH scripts occupy 2 slots of default size
+	if outputs := MaxOutputs(5, dc.DefaultSlotSize); outputs != 2 {
+		t.Error("For", 5, "expected", 2, "got", outputs)
+	}
+
+	tests := []struct {
+		inputs []utils.Input
+		slots  uint32
+		valid  bool
+	}{
+		{[]utils.Input{input(1, 0, denomination)}, 2, true},
+		{[]utils.Input{input(1, 0, denomination/2), input(1, 1, denomination/2+MaxFee)}, 2, true},
+		{[]utils.Input{input(1, 0, 2*denomination)}, 4, true},
+		// inputs of other peers would pay for second output
+		{[]utils.Input{input(1, 0, denomination)}, 4, false},
+		{[]utils.Input{input(1, 0, 1)}, 2, false},
+		// surplus is'nt returned as change
+		{[]utils.Input{input(1, 0, denomination+MaxFee+1)}, 2, false},
+		{[]utils.Input{input(1, 0, 50*denomination)}, 2, false},
+	}
+
+	for i, test := range tests {
+		if err := CheckFunds(test.inputs, test.slots, dc.DefaultSlotSize, denomination); (err == nil) != test.valid {
+			t.Error("For", i, "expected", test.valid, "got", err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package coinjoin
+
+import (
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+// EncodeInputs - converts inputs into their wire format
+// private keys are never encoded
+func EncodeInputs(inputs []utils.Input) []*messages.Input {
+	encoded := make([]*messages.Input, len(inputs))
+	for i, input := range inputs {
+		encoded[i] = &messages.Input{
+			TxHash:   input.TxHash,
+			Index:    input.Index,
+			Value:    input.Value,
+			PkScript: input.PkScript,
+		}
+	}
+	return encoded
+}
+
+// DecodeInputs - converts inputs from their wire format
+func DecodeInputs(inputs []*messages.Input) []utils.Input {
+	decoded := make([]utils.Input, 0, len(inputs))
+	for _, input := range inputs {
+		decoded = append(decoded, utils.Input{
+			TxHash:   input.GetTxHash(),
+			Index:    input.GetIndex(),
+			Value:    input.GetValue(),
+			PkScript: input.GetPkScript(),
+		})
+	}
+	return decoded
+}
+
+// EncodeSignatures - converts signatures of inputs into their wire format
+func EncodeSignatures(signatures []utils.Signature) []*messages.InputSignature {
+	encoded := make([]*messages.InputSignature, len(signatures))
+	for i, signature := range signatures {
+		encoded[i] = &messages.InputSignature{
+			TxHash:          signature.TxHash,
+			Index:           signature.Index,
+			SignatureScript: signature.SignatureScript,
+			Witness:         signature.Witness,
+		}
+	}
+	return encoded
+}
+
+// DecodeSignatures - converts signatures of inputs from their wire format
+func DecodeSignatures(signatures []*messages.InputSignature) []utils.Signature {
+	decoded := make([]utils.Signature, 0, len(signatures))
+	for _, signature := range signatures {
+		decoded = append(decoded, utils.Signature{
+			TxHash:          signature.GetTxHash(),
+			Index:           signature.GetIndex(),
+			SignatureScript: signature.GetSignatureScript(),
+			Witness:         signature.GetWitness(),
+		})
+	}
+	return decoded
+}
@@ -0,0 +1,168 @@
+package coinjoin
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// Sign - signs inputs of tx using their private keys
+// P2PKH inputs are signed via signature script, P2WPKH via witness
+func Sign(tx *wire.MsgTx, inputs []utils.Input) ([]utils.Signature, error) {
+	hashes := txscript.NewTxSigHashes(tx)
+	signatures := make([]utils.Signature, 0, len(inputs))
+
+	for i, input := range inputs {
+		index, err := find(tx, input.TxHash, input.Index)
+		if err != nil {
+			return nil, err
+		}
+		if len(input.PrivateKey) != btcec.PrivKeyBytesLen {
+			return nil, fmt.Errorf("input %d without private key", i)
+		}
+		key, pub := btcec.PrivKeyFromBytes(btcec.S256(), input.PrivateKey)
+
+		signature := utils.Signature{TxHash: input.TxHash, Index: input.Index}
+		switch txscript.GetScriptClass(input.PkScript) {
+		case txscript.PubKeyHashTy:
+			// key hash decides whether public key is compressed
+			hash := input.PkScript[3:23]
+			compressed := bytes.Equal(btcutil.Hash160(pub.SerializeCompressed()), hash)
+			if !compressed && !bytes.Equal(btcutil.Hash160(pub.SerializeUncompressed()), hash) {
+				return nil, fmt.Errorf("key of input %d does'nt match its script", i)
+			}
+			signature.SignatureScript, err = txscript.SignatureScript(tx, index, input.PkScript, txscript.SigHashAll, key, compressed)
+		case txscript.WitnessV0PubKeyHashTy:
+			if !bytes.Equal(btcutil.Hash160(pub.SerializeCompressed()), input.PkScript[2:22]) {
+				return nil, fmt.Errorf("key of input %d does'nt match its script", i)
+			}
+			signature.Witness, err = txscript.WitnessSignature(tx, hashes, index, int64(input.Value), input.PkScript, txscript.SigHashAll, key, true)
+		default:
+			return nil, fmt.Errorf("input %d is not P2PKH or P2WPKH", i)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		signatures = append(signatures, signature)
+	}
+	return signatures, nil
+}
+
+// Apply - places signatures in inputs of tx they sign
+func Apply(tx *wire.MsgTx, signatures []utils.Signature) error {
+	for _, signature := range signatures {
+		index, err := find(tx, signature.TxHash, signature.Index)
+		if err != nil {
+			return err
+		}
+		tx.TxIn[index].SignatureScript = signature.SignatureScript
+		tx.TxIn[index].Witness = signature.Witness
+	}
+	return nil
+}
+
+// VerifyInputs - executes scripts of inputs against signatures placed in tx
+func VerifyInputs(tx *wire.MsgTx, inputs []utils.Input) error {
+	hashes := txscript.NewTxSigHashes(tx)
+	for i, input := range inputs {
+		index, err := find(tx, input.TxHash, input.Index)
+		if err != nil {
+			return err
+		}
+
+		engine, err := txscript.NewEngine(input.PkScript, tx, index, txscript.StandardVerifyFlags, nil, hashes, int64(input.Value))
+		if err == nil {
+			err = engine.Execute()
+		}
+		if err != nil {
+			return fmt.Errorf("input %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// VerifySignatures - checks if signatures of peer sign every input it announced
+// and nothing else
+func VerifySignatures(unsigned *wire.MsgTx, inputs []utils.Input, signatures []utils.Signature) error {
+	if len(signatures) != len(inputs) {
+		return fmt.Errorf("%d signatures for %d inputs", len(signatures), len(inputs))
+	}
+
+	owned := make(map[string]bool, len(inputs))
+	for _, input := range inputs {
+		owned[outpoint(input.TxHash, input.Index)] = true
+	}
+	for _, signature := range signatures {
+		if !owned[outpoint(signature.TxHash, signature.Index)] {
+			return fmt.Errorf("signature of input not owned")
+		}
+	}
+
+	tx := unsigned.Copy()
+	if err := Apply(tx, signatures); err != nil {
+		return err
+	}
+	return VerifyInputs(tx, inputs)
+}
+
+// Verify - checks if final transaction is unsigned transaction
+// signed for every input and paying denomination to each of outputs
+func Verify(final, unsigned *wire.MsgTx, inputs []utils.Input, outputs [][]byte, denomination uint64) error {
+	stripped := final.Copy()
+	for _, in := range stripped.TxIn {
+		in.SignatureScript, in.Witness = nil, nil
+	}
+	if stripped.TxHash() != unsigned.TxHash() {
+		return fmt.Errorf("transaction differs from one assembled locally")
+	}
+
+	if len(inputs) != len(final.TxIn) {
+		return fmt.Errorf("transaction spends %d inputs, expected %d", len(final.TxIn), len(inputs))
+	}
+	if err := VerifyInputs(final, inputs); err != nil {
+		return err
+	}
+
+	for i, output := range outputs {
+		if !Contains(final, output, denomination) {
+			return fmt.Errorf("transaction does'nt pay to output %d", i)
+		}
+	}
+	return nil
+}
+
+// Contains - checks if tx pays denomination to script
+func Contains(tx *wire.MsgTx, script []byte, denomination uint64) bool {
+	for _, out := range tx.TxOut {
+		if out.Value == int64(denomination) && bytes.Equal(out.PkScript, script) {
+			return true
+		}
+	}
+	return false
+}
+
+// Deserialize - decodes transaction from bitcoin wire format
+func Deserialize(data []byte) (*wire.MsgTx, error) {
+	tx := &wire.MsgTx{}
+	if err := tx.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// returns index of input of tx spending output hash:index
+func find(tx *wire.MsgTx, hash []byte, index uint32) (int, error) {
+	for i, in := range tx.TxIn {
+		if bytes.Equal(in.PreviousOutPoint.Hash[:], hash) && in.PreviousOutPoint.Index == index {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("transaction does'nt spend output %x:%d", hash, index)
+}
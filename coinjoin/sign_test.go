@@ -0,0 +1,126 @@
+package coinjoin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// returns input of peer b spending its output along with key signing it
+func signable(t *testing.T, b byte, witness, compressed bool) utils.Input {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := key.PubKey().SerializeUncompressed()
+	if compressed {
+		pub = key.PubKey().SerializeCompressed()
+	}
+	script := append(append([]byte{0x76, 0xa9, 0x14}, btcutil.Hash160(pub)...), 0x88, 0xac)
+	if witness {
+		script = append([]byte{0x00, 0x14}, btcutil.Hash160(pub)...)
+	}
+
+	return utils.Input{
+		TxHash:     bytes.Repeat([]byte{b}, 32),
+		Value:      denomination,
+		PkScript:   script,
+		PrivateKey: key.Serialize(),
+	}
+}
+
+// signs tx by every peer owning one of inputs
+func signAll(t *testing.T, tx *wire.MsgTx, inputs []utils.Input) ([][]utils.Signature, *wire.MsgTx) {
+	final := tx.Copy()
+	signatures := make([][]utils.Signature, len(inputs))
+	for i, input := range inputs {
+		var err error
+		if signatures[i], err = Sign(tx, []utils.Input{input}); err != nil {
+			t.Fatal(err)
+		}
+		if err := Apply(final, signatures[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signatures, final
+}
+
+func TestSignVerify(t *testing.T) {
+	inputs := []utils.Input{signable(t, 1, true, true), signable(t, 2, false, true), signable(t, 3, false, false)}
+	outputs := [][]byte{p2pkh(1), p2wpkh(2), p2wpkh(3)}
+
+	unsigned, err := Build(inputs, outputs, denomination)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signatures, final := signAll(t, unsigned, inputs)
+	for i, input := range inputs {
+		if err := VerifySignatures(unsigned, []utils.Input{input}, signatures[i]); err != nil {
+			t.Error("For", i, "expected", nil, "got", err)
+		}
+	}
+
+	if err := Verify(final, unsigned, inputs, outputs[:1], denomination); err != nil {
+		t.Error("For", "signed transaction", "expected", nil, "got", err)
+	}
+
+	// transaction missing signature of some input is'nt broadcastable
+	partial := unsigned.Copy()
+	Apply(partial, signatures[0])
+	if err := Verify(partial, unsigned, inputs, outputs[:1], denomination); err == nil {
+		t.Error("For", "partially signed transaction", "expected", "error", "got", nil)
+	}
+
+	// server replaced our output
+	tampered := final.Copy()
+	tampered.TxOut[0].PkScript = p2wpkh(4)
+	if err := Verify(tampered, unsigned, inputs, outputs[:1], denomination); err == nil {
+		t.Error("For", "tampered transaction", "expected", "error", "got", nil)
+	}
+
+	// our output is'nt in transaction peers agreed on
+	if err := Verify(final, unsigned, inputs, [][]byte{p2wpkh(4)}, denomination); err == nil {
+		t.Error("For", "missing output", "expected", "error", "got", nil)
+	}
+}
+
+func TestVerifyInvalidSignatures(t *testing.T) {
+	inputs := []utils.Input{signable(t, 1, true, true), signable(t, 2, false, true)}
+	unsigned, _ := Build(inputs, [][]byte{p2pkh(1), p2wpkh(2)}, denomination)
+	signatures, _ := signAll(t, unsigned, inputs)
+
+	// signed with key of other input
+	forged := inputs[0]
+	forged.PrivateKey = inputs[1].PrivateKey
+	if _, err := Sign(unsigned, []utils.Input{forged}); err == nil {
+		t.Error("For", "other key", "expected", "error", "got", nil)
+	}
+
+	swapped := []utils.Signature{signatures[1][0]}
+	swapped[0].TxHash, swapped[0].Index = inputs[0].TxHash, inputs[0].Index
+
+	tests := []struct {
+		inputs     []utils.Input
+		signatures []utils.Signature
+	}{
+		// missing signature
+		{inputs[:1], nil},
+		// signature of input owned by other peer
+		{inputs[:1], signatures[1]},
+		// signature of other input placed in ours
+		{inputs[:1], swapped},
+	}
+
+	for i, test := range tests {
+		if err := VerifySignatures(unsigned, test.inputs, test.signatures); err == nil {
+			t.Error("For", i, "expected", "error", "got", nil)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package coinjoin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+// ErrNoUTXO - output is spent or unknown to UTXO source
+var ErrNoUTXO = errors.New("no unspent output")
+
+// UTXOSource - looks up unspent output txHash:index on chain
+// returns its amount and script, or ErrNoUTXO if it is spent or unknown
+type UTXOSource func(txHash []byte, index uint32) (uint64, []byte, error)
+
+// VerifyUTXOs - checks inputs announced by peers against UTXO source
+// amounts are announced by peers themselves and signatures of P2PKH inputs
+// does'nt commit to them, so CheckFunds alone can't stop peer overstating
+// its inputs to receive outputs paid by inputs of others
+func VerifyUTXOs(state *utils.State, source UTXOSource) error {
+	for _, peer := range state.Peers {
+		for i, input := range peer.Inputs {
+			value, script, err := source(input.TxHash, input.Index)
+			if errors.Is(err, ErrNoUTXO) {
+				return utils.NewPeerError([]int32{peer.ID}, "input %d: %v", i, err)
+			}
+			if err != nil {
+				return fmt.Errorf("unable to look up input %d of peer %d: %w", i, peer.ID, err)
+			}
+
+			if value != input.Value || !bytes.Equal(script, input.PkScript) {
+				return utils.NewPeerError([]int32{peer.ID}, "input %d announced with %d satoshis and script %x, output holds %d and %x",
+					i, input.Value, input.PkScript, value, script)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package coinjoin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+func TestVerifyUTXOs(t *testing.T) {
+	errLookup := errors.New("node unreachable")
+
+	// outputs on chain hold amounts and scripts as created by input
+	// outputs of peer 3 are'nt found if err is set
+	chain := func(err error) UTXOSource {
+		return func(txHash []byte, index uint32) (uint64, []byte, error) {
+			if err != nil && txHash[0] == 3 {
+				return 0, nil, err
+			}
+			utxo := input(txHash[0], index, denomination)
+			return utxo.Value, utxo.PkScript, nil
+		}
+	}
+
+	overstated := input(3, 0, denomination+1)
+	script := input(3, 1, denomination)
+	script.PkScript = p2pkh(3)
+
+	tests := []struct {
+		inputs   []utils.Input
+		source   UTXOSource
+		expected error
+	}{
+		{[]utils.Input{input(3, 0, denomination)}, chain(nil), nil},
+		{[]utils.Input{input(3, 0, denomination), overstated}, chain(nil), utils.ErrPeerMisbehaviour},
+		{[]utils.Input{script}, chain(nil), utils.ErrPeerMisbehaviour},
+		{[]utils.Input{input(3, 0, denomination)}, chain(ErrNoUTXO), utils.ErrPeerMisbehaviour},
+		// failure of source is'nt blamed on peer
+		{[]utils.Input{input(3, 0, denomination)}, chain(errLookup), errLookup},
+	}
+
+	for i, test := range tests {
+		state := &utils.State{Peers: []utils.Peers{
+			{ID: 2, Inputs: []utils.Input{input(2, 0, denomination)}},
+			{ID: 3, Inputs: test.inputs},
+		}}
+
+		err := VerifyUTXOs(state, test.source)
+		var e *utils.Error
+		if !errors.Is(err, test.expected) ||
+			errors.Is(err, utils.ErrPeerMisbehaviour) && (!errors.As(err, &e) || len(e.Peers) != 1 || e.Peers[0] != 3) {
+			t.Error("For", i, "expected", test.expected, "got", err)
+		}
+	}
+}
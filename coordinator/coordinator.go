@@ -0,0 +1,286 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config - parameters of coordinator
+type Config struct {
+	// Peers - number of peers in every run
+	Peers int
+	// Timeout - time to wait for peers in every phase of run
+	Timeout time.Duration
+}
+
+// Coordinator - reference implementation of DiceMix server
+// relays broadcasts of peers and solves DC-EXP, DC-SIMPLE
+type Coordinator struct {
+	sync.Mutex
+	config      Config
+	upgrader    websocket.Upgrader
+	nextID      int32
+	nextSession uint64
+	// peers waiting for run to start
+	pools map[pool][]*peer
+	// runs in progress mapped by session id
+	runs map[uint64]*run
+}
+
+// peers are placed in runs with same denomination, version, slot size
+// and agreeing whether DC vectors are committed to
+type pool struct {
+	denomination uint64
+	version      uint32
+	slotSize     uint32
+	commit       bool
+}
+
+// New creates a new Coordinator instance
+func New(config Config) *Coordinator {
+	return &Coordinator{
+		config: config,
+		pools:  make(map[pool][]*peer),
+		runs:   make(map[uint64]*run),
+	}
+}
+
+// ServeHTTP - upgrades request to websocket connection and serves client
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := c.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Info("Unable to upgrade connection - ", err)
+		return
+	}
+
+	c.Serve(transport.NewWebsocket(conn))
+}
+
+// ServeTCP - accepts clients on listener using length-prefixed framing
+// returns once listener is closed
+func (c *Coordinator) ServeTCP(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go c.Serve(transport.NewTCP(conn))
+	}
+}
+
+// Pipe - connects in-memory client
+// returns client end of transport
+func (c *Coordinator) Pipe() transport.Transport {
+	client, server := transport.Pipe()
+	go c.Serve(server)
+	return client
+}
+
+// Serve - handles requests of client until transport is closed
+func (c *Coordinator) Serve(conn transport.Transport) {
+	defer conn.Close()
+
+	p := &peer{conn: conn}
+	for {
+		frame, err := conn.Receive()
+		if err != nil {
+			c.disconnect(p, conn)
+			return
+		}
+
+		c.Lock()
+		p, err = c.handle(p, frame)
+		c.Unlock()
+
+		if err != nil {
+			log.Info("Closing connection of peer ", p.id, " - ", err)
+			c.disconnect(p, conn)
+			return
+		}
+	}
+}
+
+// identifies request from client
+// returns peer connection belongs to, which changes on resume
+func (c *Coordinator) handle(p *peer, frame []byte) (*peer, error) {
+	signedRequest := &messages.SignedRequest{}
+	if err := proto.Unmarshal(frame, signedRequest); err != nil {
+		return p, err
+	}
+
+	request := &messages.GenericRequest{}
+	if err := proto.Unmarshal(signedRequest.RequestData, request); err != nil || request.Header == nil {
+		return p, fmt.Errorf("malformed request")
+	}
+
+	switch request.Header.Code {
+	case messages.C_JOIN_REQUEST:
+		return p, c.join(p, signedRequest.RequestData)
+	case messages.C_LTPK_REQUEST:
+		return p, c.registerLTPK(p, signedRequest.RequestData)
+	case messages.C_RESUME_REQUEST:
+		return c.resume(p, signedRequest)
+	}
+
+	// requests in run should be signed using LTSK of peer
+	ecdsa := ecdsa.NewCurveECDSA()
+	if p.run == nil || request.Header.SessionId != p.run.id || request.Header.Id != p.id || request.Header.Run != p.run.number ||
+		!ecdsa.Verify(p.ltpk, signedRequest.RequestData, signedRequest.Signature) {
+		return p, fmt.Errorf("invalid request with code %d", request.Header.Code)
+	}
+
+	return p, p.run.receive(p, request.Header.Code, frame, signedRequest.RequestData)
+}
+
+// assigns id to peer if it can be placed in pool requested
+func (c *Coordinator) join(p *peer, data []byte) error {
+	request := &messages.JoinRequest{}
+	if err := proto.Unmarshal(data, request); err != nil {
+		return err
+	}
+
+	c.nextID++
+	p.id = c.nextID
+	p.pool = pool{request.Denomination, request.Version, request.SlotSize, request.Commit}
+	p.numMsgs = request.NumMsgs
+
+	header := responseHeader(messages.S_JOIN_RESPONSE, 0)
+	switch {
+	case request.Version != utils.ProtocolVersion:
+		header.Err = fmt.Sprintf("unsupported protocol version %d", request.Version)
+	case request.MinPeers > uint32(c.config.Peers):
+		header.Err = fmt.Sprintf("anonymity set of %d peers is not available", request.MinPeers)
+	case request.NumMsgs == 0 || request.NumMsgs > utils.MaxAllowedMessages:
+		header.Err = fmt.Sprintf("invalid number of messages %d", request.NumMsgs)
+	case request.SlotSize < dc.MinSlotSize || request.SlotSize > dc.MaxSlotSize:
+		header.Err = fmt.Sprintf("unsupported slot size %d", request.SlotSize)
+	default:
+		header.Message = "Joined pool, waiting for peers"
+	}
+
+	p.send(messages.S_JOIN_RESPONSE, &messages.RegisterResponse{
+		Header: header,
+		Id:     p.id,
+	})
+
+	if header.Err != "" {
+		return errors.New(header.Err)
+	}
+	return nil
+}
+
+// stores LTPK of peer and starts run once pool is full
+func (c *Coordinator) registerLTPK(p *peer, data []byte) error {
+	request := &messages.LtpkExchangeRequest{}
+	if err := proto.Unmarshal(data, request); err != nil {
+		return err
+	}
+
+	if p.id == 0 || p.ltpk != nil {
+		return fmt.Errorf("unexpected LTPK")
+	}
+	p.ltpk = request.PublicKey
+
+	peers := append(c.pools[p.pool], p)
+	if len(peers) < c.config.Peers {
+		c.pools[p.pool] = peers
+		return nil
+	}
+
+	delete(c.pools, p.pool)
+	c.start(peers)
+	return nil
+}
+
+// resumes run of peer over new connection
+// peer re-authenticates by signing request using its LTSK
+func (c *Coordinator) resume(p *peer, signedRequest *messages.SignedRequest) (*peer, error) {
+	request := &messages.ResumeRequest{}
+	if err := proto.Unmarshal(signedRequest.RequestData, request); err != nil {
+		return p, err
+	}
+
+	var resumed *peer
+	if r, ok := c.runs[request.Header.SessionId]; ok {
+		for _, q := range r.peers {
+			if q.id == request.Header.Id {
+				resumed = q
+			}
+		}
+	}
+
+	ecdsa := ecdsa.NewCurveECDSA()
+	header := responseHeader(messages.S_RESUME_RESPONSE, request.Header.SessionId)
+	if resumed == nil || !ecdsa.Verify(resumed.ltpk, signedRequest.RequestData, signedRequest.Signature) {
+		header.Err = "session can't be resumed"
+		p.send(messages.S_RESUME_RESPONSE, &messages.ResumeResponse{Header: header})
+		return p, nil
+	}
+
+	log.Info("Peer ", resumed.id, " resumed session ", request.Header.SessionId)
+
+	resumed.conn.Close()
+	resumed.conn = p.conn
+
+	// reports which peers have'nt delivered request of current phase
+	pending := make(map[int32]struct{})
+	for _, id := range resumed.run.pending() {
+		pending[id] = struct{}{}
+	}
+	status := resumed.run.peersInfo(func(q *peer, info *messages.PeersInfo) {
+		_, ok := pending[q.id]
+		info.MessageReceived = !ok
+	})
+
+	frame, _ := proto.Marshal(&messages.ResumeResponse{Header: header, Peers: status})
+	resumed.conn.Send(frame)
+
+	// resend response peer missed while disconnected
+	if resumed.last != nil && resumed.lastCode != request.LastCode {
+		resumed.conn.Send(resumed.last)
+	}
+
+	return resumed, nil
+}
+
+// removes peer from pool if its connection drops before run starts
+// peers of run can resume until run times out
+func (c *Coordinator) disconnect(p *peer, conn transport.Transport) {
+	c.Lock()
+	defer c.Unlock()
+
+	if p.conn != conn || p.run != nil {
+		return
+	}
+
+	peers := c.pools[p.pool]
+	for i, q := range peers {
+		if q == p {
+			c.pools[p.pool] = append(peers[:i:i], peers[i+1:]...)
+			return
+		}
+	}
+}
+
+// generates a ResponseHeader proto
+func responseHeader(code uint32, sessionID uint64) *messages.ResponseHeader {
+	return &messages.ResponseHeader{
+		Code:      code,
+		SessionId: sessionID,
+		Timestamp: time.Now().String(),
+	}
+}
@@ -0,0 +1,436 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdh"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/server"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// starts coordinator behind websocket server
+// returns address clients should connect to
+func newTestCoordinator(config Config) (*httptest.Server, string) {
+	srv := httptest.NewServer(New(config))
+	return srv, strings.TrimPrefix(srv.URL, "http://")
+}
+
+// initializes state of client mixing n messages
+func newState(n uint32, minPeers uint32) *utils.State {
+	state := &utils.State{
+		Pool:           utils.Pool{Denomination: 100000, MinPeers: minPeers, SlotSize: dc.DefaultSlotSize},
+		MyMsgCount:     n,
+		MyMessages:     make([]string, n),
+		MyMessagesHash: make([]uint64, n),
+	}
+	state.Session.Ltpk, state.Session.Ltsk, _ = ecdsa.NewCurveECDSA().GenerateKeyPair()
+	return state
+}
+
+// sends unsigned request over in-memory connection
+func sendRequest(conn transport.Transport, request proto.Message) {
+	data, _ := proto.Marshal(request)
+	frame, _ := proto.Marshal(&messages.SignedRequest{RequestData: data})
+	conn.Send(frame)
+}
+
+// runs DiceMix among peers connected via clients created by connect
+// checks every peer resolves same messages which include messages of all peers
+func testRun(t *testing.T, peers int, connect func() server.Server) {
+	states := make([]*utils.State, peers)
+	errs := make([]error, peers)
+
+	var wg sync.WaitGroup
+	for i := range states {
+		states[i] = newState(uint32(i%3+1), uint32(peers))
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = connect().Register(states[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatal("For", i, "expected", nil, "got", err)
+		}
+	}
+
+	for i, state := range states {
+		if len(state.AllMessages) != len(states[0].AllMessages) {
+			t.Fatal("For", i, "expected", states[0].AllMessages, "got", state.AllMessages)
+		}
+		for j := range state.AllMessages {
+			if !bytes.Equal(state.AllMessages[j], states[0].AllMessages[j]) {
+				t.Error("For", i, "expected", states[0].AllMessages, "got", state.AllMessages)
+			}
+		}
+
+		for _, message := range state.MyMessages {
+			found := false
+			for _, slot := range state.AllMessages {
+				found = found || bytes.Equal(slot, utils.Base58StringToBytes(message))
+			}
+			if !found {
+				t.Error("For", i, "expected", message, "got", state.AllMessages)
+			}
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	srv, addr := newTestCoordinator(Config{Peers: 4, Timeout: 10 * time.Second})
+	defer srv.Close()
+
+	testRun(t, 4, func() server.Server {
+		return server.NewConnection(addr)
+	})
+}
+
+func TestRunTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go New(Config{Peers: 3, Timeout: 10 * time.Second}).ServeTCP(listener)
+
+	testRun(t, 3, func() server.Server {
+		c := server.NewConnection(listener.Addr().String())
+		c.SetDialer(func(ctx context.Context) (transport.Transport, error) {
+			return transport.DialTCP(ctx, listener.Addr().String(), nil)
+		})
+		return c
+	})
+}
+
+func TestRunPipe(t *testing.T) {
+	coordinator := New(Config{Peers: 3, Timeout: 10 * time.Second})
+
+	testRun(t, 3, func() server.Server {
+		c := server.NewConnection("")
+		c.SetDialer(func(ctx context.Context) (transport.Transport, error) {
+			return coordinator.Pipe(), nil
+		})
+		return c
+	})
+}
+
+func TestJoinRefused(t *testing.T) {
+	srv, addr := newTestCoordinator(Config{Peers: 3, Timeout: time.Second})
+	defer srv.Close()
+
+	// anonymity set larger than coordinator provides
+	err := server.NewConnection(addr).Register(newState(1, 5))
+
+	var e *utils.Error
+	if !errors.Is(err, utils.ErrServer) || !errors.As(err, &e) || e.Code != messages.S_JOIN_RESPONSE {
+		t.Error("For", "anonymity set of 5", "expected", utils.ErrServer, "got", err)
+	}
+}
+
+func TestPipe(t *testing.T) {
+	conn := New(Config{Peers: 2, Timeout: time.Second}).Pipe()
+	defer conn.Close()
+
+	sendRequest(conn, &messages.JoinRequest{
+		Header:       &messages.RequestHeader{Code: messages.C_JOIN_REQUEST},
+		Denomination: 100000,
+		NumMsgs:      1,
+		Version:      utils.ProtocolVersion,
+		SlotSize:     dc.DefaultSlotSize,
+	})
+
+	frame, err := conn.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := &messages.RegisterResponse{}
+	proto.Unmarshal(frame, response)
+	if response.Header.Code != messages.S_JOIN_RESPONSE || response.Header.Err != "" || response.Id != 1 {
+		t.Error("For", "join", "expected", 1, "got", response)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	coordinator := New(Config{Peers: 2, Timeout: 200 * time.Millisecond})
+	srv := httptest.NewServer(coordinator)
+	defer srv.Close()
+
+	// silent peer joins over in-memory connection
+	// but never sends its KEPK
+	silent := coordinator.Pipe()
+	defer silent.Close()
+
+	sendRequest(silent, &messages.JoinRequest{
+		Header:       &messages.RequestHeader{Code: messages.C_JOIN_REQUEST},
+		Denomination: 100000,
+		NumMsgs:      1,
+		Version:      utils.ProtocolVersion,
+		SlotSize:     dc.DefaultSlotSize,
+	})
+	silent.Receive()
+	sendRequest(silent, &messages.LtpkExchangeRequest{
+		Header: &messages.RequestHeader{Code: messages.C_LTPK_REQUEST},
+	})
+
+	err := server.NewConnection(strings.TrimPrefix(srv.URL, "http://")).Register(newState(1, 2))
+
+	var e *utils.Error
+	if !errors.Is(err, utils.ErrServer) || !errors.As(err, &e) || e.Code != messages.S_KEY_EXCHANGE {
+		t.Error("For", "silent peer", "expected", utils.ErrServer, "got", err)
+	}
+}
+
+// signs request using ltsk and sends it over in-memory connection
+func sendSigned(conn transport.Transport, ltsk []byte, request proto.Message) {
+	data, _ := proto.Marshal(request)
+	frame, _ := proto.Marshal(&messages.SignedRequest{
+		RequestData: data,
+		Signature:   ecdsa.NewCurveECDSA().Sign(ltsk, data),
+	})
+	conn.Send(frame)
+}
+
+// decodes next response received over in-memory connection
+func receive(conn transport.Transport, response proto.Message) {
+	frame, _ := conn.Receive()
+	proto.Unmarshal(frame, response)
+}
+
+// peer which sends DC-EXP vector and slots of garbage
+// but follows protocol otherwise, so that blame identifies it
+// in runs with commitments it reveals DC-EXP vector other than committed instead
+// returns its id and error reported once it is excluded
+func disrupt(conn transport.Transport, minPeers uint32, commit bool) (int32, string) {
+	ecdh := ecdh.NewCurve25519ECDH()
+	ltpk, ltsk, _ := ecdsa.NewCurveECDSA().GenerateKeyPair()
+	kesk, kepk, _ := ecdh.GenerateKeyPair()
+
+	sendRequest(conn, &messages.JoinRequest{
+		Header:       &messages.RequestHeader{Code: messages.C_JOIN_REQUEST},
+		Denomination: 100000,
+		NumMsgs:      1,
+		MinPeers:     minPeers,
+		Version:      utils.ProtocolVersion,
+		SlotSize:     dc.DefaultSlotSize,
+		Commit:       commit,
+	})
+	joined := &messages.RegisterResponse{}
+	receive(conn, joined)
+	sendRequest(conn, &messages.LtpkExchangeRequest{
+		Header:    &messages.RequestHeader{Code: messages.C_LTPK_REQUEST},
+		PublicKey: ltpk,
+	})
+
+	start := &messages.DiceMixResponse{}
+	receive(conn, start)
+	header := func(code uint32) *messages.RequestHeader {
+		return &messages.RequestHeader{Code: code, SessionId: start.Header.SessionId, Id: joined.Id, Run: start.Run}
+	}
+
+	sendSigned(conn, ltsk, &messages.KeyExchangeRequest{
+		Header:    header(messages.C_KEY_EXCHANGE),
+		PublicKey: ecdh.Marshal(kepk),
+		NumMsgs:   1,
+	})
+	keyExchange := &messages.DiceMixResponse{}
+	receive(conn, keyExchange)
+
+	var total uint32
+	for _, peer := range keyExchange.Peers {
+		total += peer.NumMsgs
+	}
+	vector := make([]uint64, total)
+	slots := make([][]byte, total)
+	for i := range vector {
+		vector[i] = uint64(i + 1)
+		slots[i] = bytes.Repeat([]byte{1}, dc.DefaultSlotSize)
+	}
+
+	if commit {
+		committed := append([]uint64{}, vector...)
+		committed[0]++
+		sendSigned(conn, ltsk, &messages.CommitRequest{
+			Header:     header(messages.C_EXP_DC_COMMIT),
+			Commitment: dc.CommitDCExp(start.Header.SessionId, start.Run, joined.Id, committed),
+		})
+		receive(conn, &messages.DiceMixResponse{})
+	}
+
+	sendSigned(conn, ltsk, &messages.DCExpRequest{Header: header(messages.C_EXP_DC_VECTOR), DCExpVector: vector})
+	receive(conn, &messages.DCExpResponse{})
+	if !commit {
+		sendSigned(conn, ltsk, &messages.DCSimpleRequest{Header: header(messages.C_SIMPLE_DC_VECTOR), DCSimpleVector: slots, MyOk: true})
+		receive(conn, &messages.DCSimpleResponse{})
+		sendSigned(conn, ltsk, &messages.ConfirmationRequest{Header: header(messages.C_TX_CONFIRMATION)})
+	}
+	receive(conn, &messages.InitiaiteKESK{})
+	sendSigned(conn, ltsk, &messages.InitiaiteKESKResponse{Header: header(messages.C_KESK_RESPONSE), PrivateKey: ecdh.MarshalSK(kesk)})
+	receive(conn, &messages.DiceMixResponse{})
+
+	excluded := &messages.GenericResponse{}
+	receive(conn, excluded)
+	return joined.Id, excluded.Header.GetErr()
+}
+
+// generates message filling single slot
+func slotMessage() []byte {
+	slots, _ := dc.Pack(utils.GenerateMessage(dc.SlotCapacity(dc.DefaultSlotSize)), dc.DefaultSlotSize)
+	return slots[0]
+}
+
+// runs session among 3 honest peers and disruptor
+// honest peers, with fresh messages provided by fresh if any, should exclude disruptor
+// returns states and errors of honest peers and id of disruptor
+func runExclusion(t *testing.T, commit bool, fresh func(run uint32) ([][]byte, error)) ([]*utils.State, []error, int32) {
+	coordinator := New(Config{Peers: 4, Timeout: 10 * time.Second})
+	srv := httptest.NewServer(coordinator)
+	defer srv.Close()
+
+	disruptor := coordinator.Pipe()
+	defer disruptor.Close()
+
+	var id int32
+	var reason string
+	done := make(chan struct{})
+	go func() {
+		id, reason = disrupt(disruptor, 3, commit)
+		close(done)
+	}()
+
+	// honest peers continue session without disruptor
+	states := make([]*utils.State, 3)
+	errs := make([]error, len(states))
+
+	var wg sync.WaitGroup
+	for i := range states {
+		states[i] = newState(1, 3)
+		states[i].Pool.Commit = commit
+		states[i].MyMessages[0] = utils.BytesToBase58String(slotMessage())
+
+		conn := server.NewConnection(strings.TrimPrefix(srv.URL, "http://"))
+		if fresh != nil {
+			conn.SetFreshMessages(fresh)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = conn.Register(states[i])
+		}(i)
+	}
+	wg.Wait()
+	<-done
+
+	if reason == "" {
+		t.Error("For", "disruptor", "expected", "exclusion", "got", reason)
+	}
+	return states, errs, id
+}
+
+// honest peers should mix their messages in second run
+// messages are replaced only if blame unmasked them after DC-SIMPLE
+func testExclusion(t *testing.T, commit bool) {
+	var mu sync.Mutex
+	provided := make(map[string]struct{})
+	states, errs, id := runExclusion(t, commit, func(run uint32) ([][]byte, error) {
+		if run != 2 {
+			t.Error("For", "fresh messages", "expected", 2, "got", run)
+		}
+
+		message := slotMessage()
+		mu.Lock()
+		provided[utils.BytesToBase58String(message)] = struct{}{}
+		mu.Unlock()
+		return [][]byte{message}, nil
+	})
+
+	for i, state := range states {
+		if errs[i] != nil {
+			t.Fatal("For", i, "expected", nil, "got", errs[i])
+		}
+		if state.Run != 2 || len(state.Peers) != 2 {
+			t.Error("For", i, "expected", "2 runs among 3 peers", "got", state.Run, len(state.Peers))
+		}
+		if len(state.MaliciousPeers) != 1 || state.MaliciousPeers[0] != id {
+			t.Error("For", i, "expected", []int32{id}, "got", state.MaliciousPeers)
+		}
+		if len(state.AllMessages) != len(states) {
+			t.Error("For", i, "expected", len(states), "got", len(state.AllMessages))
+		}
+
+		// blame after DC-SIMPLE replaces messages mixed, blame before it does'nt
+		if _, ok := provided[state.MyMessages[0]]; ok == commit {
+			t.Error("For", i, "expected", "fresh message mixed", !commit, "got", ok)
+		}
+	}
+
+	if commit && len(provided) != 0 || !commit && len(provided) != len(states) {
+		t.Error("For", commit, "expected", "messages replaced only after DC-SIMPLE", "got", len(provided))
+	}
+}
+
+func TestExclusion(t *testing.T) {
+	testExclusion(t, false)
+}
+
+// disruptor reveals DC-EXP vector which does'nt match its commitment
+// blame is initiated before DC-SIMPLE, so messages are mixed again
+func TestExclusionCommitment(t *testing.T) {
+	testExclusion(t, true)
+}
+
+// KESK revealed after DC-SIMPLE unmasks messages of honest peers
+// they should'nt mix them again without fresh ones
+func TestExclusionExposed(t *testing.T) {
+	states, errs, id := runExclusion(t, false, nil)
+
+	for i := range states {
+		var e *utils.Error
+		if !errors.Is(errs[i], utils.ErrPeerMisbehaviour) || !errors.As(errs[i], &e) || len(e.Peers) != 1 || e.Peers[0] != id {
+			t.Error("For", i, "expected", utils.ErrPeerMisbehaviour, "got", errs[i])
+		}
+	}
+}
+
+func TestExclusionAbort(t *testing.T) {
+	coordinator := New(Config{Peers: 2, Timeout: 10 * time.Second})
+	srv := httptest.NewServer(coordinator)
+	defer srv.Close()
+
+	disruptor := coordinator.Pipe()
+	defer disruptor.Close()
+
+	ids := make(chan int32, 1)
+	go func() {
+		id, _ := disrupt(disruptor, 2, false)
+		ids <- id
+	}()
+
+	// single peer remains, session can't continue
+	err := server.NewConnection(strings.TrimPrefix(srv.URL, "http://")).Register(newState(1, 2))
+	id := <-ids
+
+	var e *utils.Error
+	if !errors.Is(err, utils.ErrPeerMisbehaviour) || !errors.As(err, &e) || len(e.Peers) != 1 || e.Peers[0] != id {
+		t.Error("For", "single remaining peer", "expected", utils.ErrPeerMisbehaviour, "got", err)
+	}
+}
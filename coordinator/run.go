@@ -0,0 +1,535 @@
+package coordinator
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/coinjoin"
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/field"
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/solver"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/golang/protobuf/proto"
+	log "github.com/sirupsen/logrus"
+)
+
+// client connected to coordinator
+// stores everything peer broadcasted in current run
+type peer struct {
+	id      int32
+	conn    transport.Transport
+	pool    pool
+	numMsgs uint32
+	ltpk    []byte
+	run     *run
+	// signed requests of peer in current run mapped by code
+	requests       map[uint32][]byte
+	kepk           []byte
+	inputs         []*messages.Input
+	dcExpCommit    []byte
+	dcVector       []uint64
+	dcSimpleCommit []byte
+	dcSimpleVector [][]byte
+	ok             bool
+	nextKepk       []byte
+	confirmation   bool
+	signatures     []*messages.InputSignature
+	kesk           []byte
+	// last response sent to peer, resent on resume
+	last     []byte
+	lastCode uint32
+}
+
+// clears everything peer broadcasted in previous run of session
+func (p *peer) clear() {
+	p.requests = make(map[uint32][]byte)
+	p.kepk, p.inputs, p.nextKepk, p.kesk = nil, nil, nil, nil
+	p.dcExpCommit, p.dcSimpleCommit = nil, nil
+	p.dcVector, p.dcSimpleVector, p.ok = nil, nil, false
+	p.confirmation, p.signatures = false, nil
+}
+
+// sends response to peer
+// failures are ignored as peer can resume later
+func (p *peer) send(code uint32, response proto.Message) {
+	frame, err := proto.Marshal(response)
+	if err != nil {
+		return
+	}
+
+	p.last, p.lastCode = frame, code
+	p.conn.Send(frame)
+}
+
+// DiceMix session among peers
+// consists of runs, every next run excludes peers blamed in previous one
+// number - number of current run within session
+// expects - code of requests run waits for
+type run struct {
+	coordinator *Coordinator
+	id          uint64
+	number      uint32
+	peers       []*peer
+	expects     uint32
+	timer       *time.Timer
+	// message hashes solved from DC-EXP, required to blame peers
+	roots []uint64
+}
+
+// response sent once every peer delivers request with code
+var responseCodes = map[uint32]uint32{
+	messages.C_KEY_EXCHANGE:     messages.S_KEY_EXCHANGE,
+	messages.C_EXP_DC_COMMIT:    messages.S_EXP_DC_COMMIT,
+	messages.C_EXP_DC_VECTOR:    messages.S_EXP_DC_VECTOR,
+	messages.C_SIMPLE_DC_COMMIT: messages.S_SIMPLE_DC_COMMIT,
+	messages.C_SIMPLE_DC_VECTOR: messages.S_SIMPLE_DC_VECTOR,
+	messages.C_TX_CONFIRMATION:  messages.S_TX_SUCCESSFUL,
+	messages.C_KESK_RESPONSE:    messages.S_BLAME,
+}
+
+// starts session among peers
+func (c *Coordinator) start(peers []*peer) {
+	c.nextSession++
+	r := &run{
+		coordinator: c,
+		id:          c.nextSession,
+	}
+	c.runs[r.id] = r
+
+	r.next(peers)
+}
+
+// starts next run of session among peers
+func (r *run) next(peers []*peer) {
+	r.number++
+	r.peers = peers
+	r.roots = nil
+
+	info := make([]*messages.PeersInfo, len(peers))
+	for i, p := range peers {
+		p.run = r
+		p.clear()
+		info[i] = &messages.PeersInfo{
+			Id:          p.id,
+			LTPublicKey: p.ltpk,
+			NumMsgs:     p.numMsgs,
+		}
+	}
+
+	log.Info("Starting run ", r.number, " of session ", r.id, " with ", len(peers), " peers")
+
+	r.broadcast(messages.S_START_DICEMIX, &messages.DiceMixResponse{
+		Header:       responseHeader(messages.S_START_DICEMIX, r.id),
+		Peers:        info,
+		Denomination: peers[0].pool.denomination,
+		Version:      peers[0].pool.version,
+		SlotSize:     peers[0].pool.slotSize,
+		Run:          r.number,
+		Commit:       peers[0].pool.commit,
+	})
+	r.await(messages.C_KEY_EXCHANGE)
+}
+
+// stores request of peer
+// moves run forward once every peer has delivered
+func (r *run) receive(p *peer, code uint32, frame, data []byte) error {
+	// peer resends its last request on resume
+	if _, ok := p.requests[code]; ok {
+		return nil
+	}
+	if code != r.expects {
+		return fmt.Errorf("unexpected request with code %d, expected %d", code, r.expects)
+	}
+
+	var err error
+	switch code {
+	case messages.C_KEY_EXCHANGE:
+		request := &messages.KeyExchangeRequest{}
+		if err = proto.Unmarshal(data, request); err == nil {
+			p.kepk, p.numMsgs, p.inputs = request.PublicKey, request.NumMsgs, request.Inputs
+		}
+	case messages.C_EXP_DC_COMMIT:
+		request := &messages.CommitRequest{}
+		if err = proto.Unmarshal(data, request); err == nil {
+			p.dcExpCommit = request.Commitment
+		}
+	case messages.C_SIMPLE_DC_COMMIT:
+		request := &messages.CommitRequest{}
+		if err = proto.Unmarshal(data, request); err == nil {
+			p.dcSimpleCommit = request.Commitment
+		}
+	case messages.C_EXP_DC_VECTOR:
+		request := &messages.DCExpRequest{}
+		if err = proto.Unmarshal(data, request); err == nil {
+			p.dcVector = request.DCExpVector
+		}
+	case messages.C_SIMPLE_DC_VECTOR:
+		request := &messages.DCSimpleRequest{}
+		if err = proto.Unmarshal(data, request); err == nil {
+			p.dcSimpleVector, p.ok, p.nextKepk = request.DCSimpleVector, request.MyOk, request.NextPublicKey
+		}
+	case messages.C_TX_CONFIRMATION:
+		request := &messages.ConfirmationRequest{}
+		if err = proto.Unmarshal(data, request); err == nil {
+			p.confirmation, p.signatures = request.Confirmation, request.Signatures
+		}
+	case messages.C_KESK_RESPONSE:
+		request := &messages.InitiaiteKESKResponse{}
+		if err = proto.Unmarshal(data, request); err == nil {
+			p.kesk = request.PrivateKey
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	p.requests[code] = frame
+	if len(r.pending()) == 0 {
+		r.advance()
+	}
+	return nil
+}
+
+// broadcasts response of current phase and moves to next one
+func (r *run) advance() {
+	r.timer.Stop()
+
+	switch r.expects {
+	case messages.C_KEY_EXCHANGE:
+		r.broadcast(messages.S_KEY_EXCHANGE, &messages.DiceMixResponse{
+			Header: responseHeader(messages.S_KEY_EXCHANGE, r.id),
+			Peers: r.peersInfo(func(p *peer, info *messages.PeersInfo) {
+				info.PublicKey, info.NumMsgs, info.Inputs = p.kepk, p.numMsgs, p.inputs
+			}, messages.C_KEY_EXCHANGE),
+		})
+		r.await(r.commitOr(messages.C_EXP_DC_COMMIT, messages.C_EXP_DC_VECTOR))
+
+	case messages.C_EXP_DC_COMMIT:
+		// vectors are relayed only once every peer has committed
+		r.broadcast(messages.S_EXP_DC_COMMIT, &messages.DiceMixResponse{
+			Header: responseHeader(messages.S_EXP_DC_COMMIT, r.id),
+			Peers: r.peersInfo(func(p *peer, info *messages.PeersInfo) {
+				info.Commitment = p.dcExpCommit
+			}, messages.C_EXP_DC_COMMIT),
+		})
+		r.await(messages.C_EXP_DC_VECTOR)
+
+	case messages.C_EXP_DC_VECTOR:
+		r.roots = r.solveDCExp()
+		r.broadcast(messages.S_EXP_DC_VECTOR, &messages.DCExpResponse{
+			Header: responseHeader(messages.S_EXP_DC_VECTOR, r.id),
+			Roots:  r.roots,
+			Peers: r.peersInfo(func(p *peer, info *messages.PeersInfo) {
+				info.DCVector = p.dcVector
+			}, messages.C_EXP_DC_VECTOR),
+		})
+
+		// peers does'nt reveal anything more once some vector does'nt match its commitment
+		if mismatched := r.mismatched(); len(mismatched) != 0 {
+			log.Info("Peers ", mismatched, " revealed DC-EXP vectors not committed to in session ", r.id)
+			r.initiateBlame()
+			return
+		}
+		r.await(r.commitOr(messages.C_SIMPLE_DC_COMMIT, messages.C_SIMPLE_DC_VECTOR))
+
+	case messages.C_SIMPLE_DC_COMMIT:
+		r.broadcast(messages.S_SIMPLE_DC_COMMIT, &messages.DiceMixResponse{
+			Header: responseHeader(messages.S_SIMPLE_DC_COMMIT, r.id),
+			Peers: r.peersInfo(func(p *peer, info *messages.PeersInfo) {
+				info.Commitment = p.dcSimpleCommit
+			}, messages.C_SIMPLE_DC_COMMIT),
+		})
+		r.await(messages.C_SIMPLE_DC_VECTOR)
+
+	case messages.C_SIMPLE_DC_VECTOR:
+		r.broadcast(messages.S_SIMPLE_DC_VECTOR, &messages.DCSimpleResponse{
+			Header:   responseHeader(messages.S_SIMPLE_DC_VECTOR, r.id),
+			Messages: r.solveDCSimple(),
+			Peers: r.peersInfo(func(p *peer, info *messages.PeersInfo) {
+				info.DCSimpleVector, info.OK, info.NextPublicKey = p.dcSimpleVector, p.ok, p.nextKepk
+			}, messages.C_SIMPLE_DC_VECTOR),
+		})
+		r.await(messages.C_TX_CONFIRMATION)
+
+	case messages.C_TX_CONFIRMATION:
+		// some peer disagrees or revealed DC-SIMPLE vector not committed to
+		for _, p := range r.peers {
+			if !p.confirmation {
+				r.initiateBlame()
+				return
+			}
+		}
+		if mismatched := r.mismatched(); len(mismatched) != 0 {
+			log.Info("Peers ", mismatched, " revealed DC-SIMPLE vectors not committed to in session ", r.id)
+			r.initiateBlame()
+			return
+		}
+
+		header := responseHeader(messages.S_TX_SUCCESSFUL, r.id)
+		header.Message = "Transaction successful"
+		r.broadcast(messages.S_TX_SUCCESSFUL, &messages.TXDoneResponse{
+			Header:      header,
+			Transaction: r.transaction(),
+			Peers: r.peersInfo(func(p *peer, info *messages.PeersInfo) {
+				info.Confirmation, info.Signatures = p.confirmation, p.signatures
+			}, messages.C_TX_CONFIRMATION),
+		})
+		r.finish()
+
+	case messages.C_KESK_RESPONSE:
+		// peers need every vector to recompute them from revealed KESK's
+		r.broadcast(messages.S_BLAME, &messages.DiceMixResponse{
+			Header: responseHeader(messages.S_BLAME, r.id),
+			Peers: r.peersInfo(func(p *peer, info *messages.PeersInfo) {
+				info.PrivateKey, info.DCVector = p.kesk, p.dcVector
+				info.DCSimpleVector, info.OK, info.NextPublicKey = p.dcSimpleVector, p.ok, p.nextKepk
+			}, messages.C_KESK_RESPONSE, messages.C_EXP_DC_VECTOR, messages.C_SIMPLE_DC_VECTOR),
+		})
+		r.exclude(r.blame())
+	}
+}
+
+// requests peers to reveal their KESK's to blame peers which disrupted run
+func (r *run) initiateBlame() {
+	r.broadcast(messages.S_KESK_REQUEST, &messages.InitiaiteKESK{
+		Header: responseHeader(messages.S_KESK_REQUEST, r.id),
+	})
+	r.await(messages.C_KESK_RESPONSE)
+}
+
+// returns ids of peers whose revealed DC vectors does'nt match their commitments
+// DC-SIMPLE vectors are checked only once revealed
+func (r *run) mismatched() []int32 {
+	ids := make([]int32, 0)
+	if !r.peers[0].pool.commit {
+		return ids
+	}
+
+	for _, p := range r.peers {
+		if !bytes.Equal(p.dcExpCommit, dc.CommitDCExp(r.id, r.number, p.id, p.dcVector)) ||
+			p.dcSimpleVector != nil && !bytes.Equal(p.dcSimpleCommit, dc.CommitDCSimple(r.id, r.number, p.id, p.dcSimpleVector)) {
+			ids = append(ids, p.id)
+		}
+	}
+	return ids
+}
+
+// identifies peers which disrupted run from KESK's they revealed
+// same way as every peer does, so that they agree on peers excluded
+func (r *run) blame() []int32 {
+	state := &utils.State{AllMsgHashes: r.roots, Run: r.number}
+	state.Session.SessionID = r.id
+	state.Pool.SlotSize, state.Pool.Commit = r.peers[0].pool.slotSize, r.peers[0].pool.commit
+	for _, p := range r.peers {
+		state.Peers = append(state.Peers, utils.Peers{
+			ID:                 p.id,
+			PubKey:             p.kepk,
+			Kesk:               p.kesk,
+			NumMsgs:            p.numMsgs,
+			DCVector:           p.dcVector,
+			DCSimpleVector:     p.dcSimpleVector,
+			Ok:                 p.ok,
+			DCExpCommitment:    p.dcExpCommit,
+			DCSimpleCommitment: p.dcSimpleCommit,
+		})
+	}
+	return dc.NewDCNetwork().Blame(state)
+}
+
+// starts next run of session without peers blamed
+// session is aborted if nobody is blamed or too few peers remain
+func (r *run) exclude(blamed []int32) {
+	excluded := make(map[int32]bool, len(blamed))
+	for _, id := range blamed {
+		excluded[id] = true
+	}
+
+	remaining := make([]*peer, 0, len(r.peers))
+	for _, p := range r.peers {
+		if !excluded[p.id] {
+			remaining = append(remaining, p)
+			continue
+		}
+
+		header := responseHeader(messages.S_START_DICEMIX, r.id)
+		header.Err = "excluded from session for disrupting run"
+		p.send(messages.S_START_DICEMIX, &messages.GenericResponse{Header: header})
+		p.run = nil
+	}
+
+	switch {
+	case len(blamed) == 0:
+		r.abort(messages.S_START_DICEMIX, "no peer could be blamed for disrupting run")
+	case len(remaining) < 2:
+		r.abort(messages.S_START_DICEMIX, fmt.Sprintf("only %d peers remain after excluding %v", len(remaining), blamed))
+	default:
+		log.Info("Excluding peers ", blamed, " from session ", r.id)
+		r.next(remaining)
+	}
+}
+
+// returns commit in runs committing to DC vectors, code otherwise
+func (r *run) commitOr(commit, code uint32) uint32 {
+	if r.peers[0].pool.commit {
+		return commit
+	}
+	return code
+}
+
+// waits for peers to deliver requests with code
+// aborts run if they does'nt within timeout
+func (r *run) await(code uint32) {
+	r.expects = code
+	r.timer = time.AfterFunc(r.coordinator.config.Timeout, func() {
+		r.coordinator.Lock()
+		defer r.coordinator.Unlock()
+
+		if r.expects == code {
+			r.abort(responseCodes[code], fmt.Sprintf("peers %v did'nt respond in time", r.pending()))
+		}
+	})
+}
+
+// informs peers waiting for response with code that run can't proceed
+func (r *run) abort(code uint32, reason string) {
+	log.Info("Aborting run ", r.id, " - ", reason)
+
+	header := responseHeader(code, r.id)
+	header.Err = reason
+	r.broadcast(header.Code, &messages.GenericResponse{Header: header})
+	r.finish()
+}
+
+// removes run from coordinator
+func (r *run) finish() {
+	r.expects = 0
+	delete(r.coordinator.runs, r.id)
+}
+
+// returns ids of peers which have'nt delivered request expected
+func (r *run) pending() []int32 {
+	ids := make([]int32, 0)
+	for _, p := range r.peers {
+		if _, ok := p.requests[r.expects]; !ok {
+			ids = append(ids, p.id)
+		}
+	}
+	return ids
+}
+
+func (r *run) broadcast(code uint32, response proto.Message) {
+	for _, p := range r.peers {
+		p.send(code, response)
+	}
+}
+
+// generates info of every peer filled by fill
+// along with its signed requests with codes, so that receivers can verify it
+func (r *run) peersInfo(fill func(*peer, *messages.PeersInfo), codes ...uint32) []*messages.PeersInfo {
+	info := make([]*messages.PeersInfo, len(r.peers))
+	for i, p := range r.peers {
+		info[i] = &messages.PeersInfo{
+			Id:              p.id,
+			MessageReceived: true,
+		}
+		fill(p, info[i])
+
+		for _, code := range codes {
+			if signedRequest, ok := p.requests[code]; ok {
+				info[i].SignedRequests = append(info[i].SignedRequests, signedRequest)
+			}
+		}
+	}
+	return info
+}
+
+// combines DC-EXP vectors of peers to obtain power sums of messages
+// returns message hashes solved from them, none in case of collision or disruption
+func (r *run) solveDCExp() []uint64 {
+	var total uint32
+	for _, p := range r.peers {
+		total += p.numMsgs
+	}
+
+	sums := make([]uint64, total)
+	for _, p := range r.peers {
+		if uint32(len(p.dcVector)) != total {
+			return []uint64{}
+		}
+		for i := range sums {
+			sums[i] = field.NewField(sums[i]).Add(field.NewField(p.dcVector[i])).Value()
+		}
+	}
+
+	roots, err := solver.Solve(sums)
+	if err != nil {
+		return []uint64{}
+	}
+	return roots
+}
+
+// combines DC-SIMPLE vectors of peers to obtain messages in their slots
+func (r *run) solveDCSimple() [][]byte {
+	var slots [][]byte
+	for _, p := range r.peers {
+		if slots == nil {
+			slots = make([][]byte, len(p.dcSimpleVector))
+		}
+		if len(p.dcSimpleVector) != len(slots) {
+			return [][]byte{}
+		}
+
+		for j := range slots {
+			if slots[j] == nil {
+				slots[j] = make([]byte, len(p.dcSimpleVector[j]))
+			}
+			if len(p.dcSimpleVector[j]) != len(slots[j]) {
+				return [][]byte{}
+			}
+			for k := range slots[j] {
+				slots[j][k] ^= p.dcSimpleVector[j][k]
+			}
+		}
+	}
+	return slots
+}
+
+// assembles coinjoin transaction signed by peers
+// none if peers does'nt spend inputs, peers reject invalid transaction
+func (r *run) transaction() []byte {
+	var inputs []utils.Input
+	var signatures []utils.Signature
+	for _, p := range r.peers {
+		inputs = append(inputs, coinjoin.DecodeInputs(p.inputs)...)
+		signatures = append(signatures, coinjoin.DecodeSignatures(p.signatures)...)
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	data, err := assemble(inputs, signatures, r.solveDCSimple(), r.peers[0].pool.denomination)
+	if err != nil {
+		log.Info("Unable to assemble transaction of run ", r.id, " - ", err)
+	}
+	return data
+}
+
+// builds transaction paying to outputs resolved in DC-SIMPLE and signs it
+func assemble(inputs []utils.Input, signatures []utils.Signature, slots [][]byte, denomination uint64) ([]byte, error) {
+	scripts, err := coinjoin.Outputs(slots)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := coinjoin.Build(inputs, scripts, denomination)
+	if err != nil {
+		return nil, err
+	}
+	if err := coinjoin.Apply(tx, signatures); err != nil {
+		return nil, err
+	}
+	return coinjoin.Serialize(tx)
+}
@@ -0,0 +1,197 @@
+package dc
+
+import (
+	"bytes"
+
+	"github.com/dev-appmonsters/dicemix-light-client/ecdh"
+	"github.com/dev-appmonsters/dicemix-light-client/field"
+	"github.com/dev-appmonsters/dicemix-light-client/rng"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+// Blame - identifies peers which disrupted current run
+// every peer reveals its KESK in blame stage, using which we re-derive
+// randomness it used to pad its DC-EXP and DC-SIMPLE vectors
+// returns Id's of peers whose broadcasts does'nt match
+func (d *dcNet) Blame(state *utils.State) []int32 {
+	ecdh := ecdh.NewCurve25519ECDH()
+	totalMsgsCount := messageCount(state.MyMsgCount, state.Peers)
+
+	// KEPK's announced by every participant of current run (including us)
+	// server blames peers as observer, without KEPK of its own
+	kepks := make(map[int32][]byte, len(state.Peers)+1)
+	if state.Session.Kepk != nil {
+		kepks[state.Session.MyID] = ecdh.Marshal(state.Session.Kepk)
+	}
+	for _, peer := range state.Peers {
+		kepks[peer.ID] = peer.PubKey
+	}
+
+	malicious := make([]int32, 0)
+	for _, peer := range state.Peers {
+		if !d.verifyCommitments(state, peer) || !d.verifyPeer(peer, kepks, state.AllMsgHashes, totalMsgsCount, int(state.Pool.SlotSize)) {
+			malicious = append(malicious, peer.ID)
+		}
+	}
+
+	d.logger.Info("Malicious peers = ", malicious)
+
+	return malicious
+}
+
+// checks if DC vectors revealed by peer match commitments it sent before
+// in runs committing to DC vectors, peer revealing other vector is blamed
+func (d *dcNet) verifyCommitments(state *utils.State, peer utils.Peers) bool {
+	if !state.Pool.Commit {
+		return true
+	}
+
+	if !bytes.Equal(peer.DCExpCommitment, CommitDCExp(state.Session.SessionID, state.Run, peer.ID, peer.DCVector)) {
+		d.logger.Info("Peer ", peer.ID, " revealed DC-EXP vector which does'nt match its commitment")
+		return false
+	}
+
+	// blame could be initiated before DC-SIMPLE vectors are revealed
+	if len(peer.DCSimpleVector) != 0 &&
+		!bytes.Equal(peer.DCSimpleCommitment, CommitDCSimple(state.Session.SessionID, state.Run, peer.ID, peer.DCSimpleVector)) {
+		d.logger.Info("Peer ", peer.ID, " revealed DC-SIMPLE vector which does'nt match its commitment")
+		return false
+	}
+	return true
+}
+
+// recomputes DC-EXP and DC-SIMPLE vectors of peer from its revealed KESK
+// returns false if they does'nt match with vectors peer broadcasted
+func (d *dcNet) verifyPeer(peer utils.Peers, kepks map[int32][]byte, allMsgHashes []uint64, totalMsgsCount uint32, slotSize int) bool {
+	ecdh := ecdh.NewCurve25519ECDH()
+
+	// revealed KESK should correspond to KEPK announced by peer in KeyExchange
+	kesk, ok := ecdh.UnmarshalSK(peer.Kesk)
+	if !ok || !bytes.Equal(ecdh.Marshal(ecdh.DerivePublicKey(kesk)), peer.PubKey) {
+		d.logger.Info("Peer ", peer.ID, " revealed KESK which does'nt match its KEPK")
+		return false
+	}
+
+	if uint32(len(peer.DCVector)) != totalMsgsCount {
+		d.logger.Info("Peer ", peer.ID, " sent DC-EXP vector of invalid length")
+		return false
+	}
+
+	// blame could be initiated before DC-SIMPLE
+	// in that case peer has'nt sent its slots
+	simple := len(peer.DCSimpleVector) != 0
+	if simple && uint32(len(peer.DCSimpleVector)) != totalMsgsCount {
+		d.logger.Info("Peer ", peer.ID, " sent DC-SIMPLE vector of invalid length")
+		return false
+	}
+
+	dcVector := make([]field.Field, totalMsgsCount)
+	for i := range dcVector {
+		dcVector[i] = field.NewField(peer.DCVector[i])
+	}
+
+	slots := make([][]byte, len(peer.DCSimpleVector))
+	for j := range slots {
+		if len(peer.DCSimpleVector[j]) != slotSize {
+			d.logger.Info("Peer ", peer.ID, " sent slot of invalid size")
+			return false
+		}
+		slots[j] = make([]byte, slotSize)
+		copy(slots[j], peer.DCSimpleVector[j])
+	}
+
+	// re-derive pairwise streams of peer with every other participant
+	// and remove padding from its vectors
+	for id, kepk := range kepks {
+		if id == peer.ID {
+			continue
+		}
+
+		pubkey, ok := ecdh.Unmarshal(kepk)
+		if !ok {
+			continue
+		}
+
+		sharedKey, err := ecdh.GenerateSharedSecret(kesk, pubkey)
+		if err != nil {
+			return false
+		}
+		dicemix, err := rng.NewRng(sharedKey)
+		if err != nil {
+			return false
+		}
+
+		// dc[i] := dc[i] (-) (sgn(peer.id - id) (*) dicemix.get_field_element())
+		var op2 = field.NewField(dicemix.GetFieldElement())
+		if peer.ID < id {
+			op2 = op2.Neg()
+		}
+		for i := range dcVector {
+			dcVector[i] = dcVector[i].Sub(op2)
+		}
+
+		// slot[j] := slot[j] (+) <randomness for chacha20>
+		for j := range slots {
+			xorBytes(slots[j], slots[j], dicemix.GetBytes(slotSize))
+		}
+	}
+
+	// without slots peer's messages can't be recovered
+	if !simple {
+		return true
+	}
+
+	// recover messages of peer from non empty slots
+	indices := make([]int, 0)
+	hashes := make([]uint64, 0)
+	for j, slot := range slots {
+		if !bytes.Equal(slot, make([]byte, slotSize)) {
+			indices = append(indices, j)
+			hashes = append(hashes, shortHash(utils.BytesToBase58String(slot)))
+		}
+	}
+
+	if uint32(len(hashes)) != peer.NumMsgs {
+		d.logger.Info("Peer ", peer.ID, " sent ", len(hashes), " messages, announced ", peer.NumMsgs)
+		return false
+	}
+
+	// DC-EXP vector should contain power sums of recovered messages
+	sums := powerSums(hashes, totalMsgsCount)
+	for i := range sums {
+		if sums[i] != dcVector[i].Value() {
+			d.logger.Info("Peer ", peer.ID, " sent DC-EXP vector inconsistent with its DC-SIMPLE vector")
+			return false
+		}
+	}
+
+	if peer.Ok {
+		// messages should be stored in slots reserved by their roots
+		for k, j := range indices {
+			if uint32(len(allMsgHashes)) != totalMsgsCount || allMsgHashes[j] != reduce(hashes[k]) {
+				d.logger.Info("Peer ", peer.ID, " stored message in slot not reserved for it")
+				return false
+			}
+		}
+		return true
+	}
+
+	// peer claimed one of its roots is missing
+	// so it should have used deterministic slots
+	missing := false
+	for k, j := range indices {
+		if j != k {
+			d.logger.Info("Peer ", peer.ID, " did'nt use deterministic slots")
+			return false
+		}
+		if !uniqueRoot(allMsgHashes, hashes[k]) {
+			missing = true
+		}
+	}
+
+	if !missing {
+		d.logger.Info("Peer ", peer.ID, " falsely claimed its message hash is missing")
+	}
+
+	return missing
+}
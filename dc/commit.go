@@ -0,0 +1,41 @@
+package dc
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+)
+
+// CommitDCExp - SHA-256 commitment of peer id to its DC-EXP vector
+// in run of session, binding vector to peer and run
+// so that commitments of other peers can't be replayed
+func CommitDCExp(sessionID uint64, run uint32, id int32, vector []uint64) []byte {
+	h := commitment("DC-EXP", sessionID, run, id)
+	binary.Write(h, binary.BigEndian, uint32(len(vector)))
+	for _, v := range vector {
+		binary.Write(h, binary.BigEndian, v)
+	}
+	return h.Sum(nil)
+}
+
+// CommitDCSimple - SHA-256 commitment of peer id to its DC-SIMPLE vector
+// in run of session, every slot is prefixed with its length
+func CommitDCSimple(sessionID uint64, run uint32, id int32, slots [][]byte) []byte {
+	h := commitment("DC-SIMPLE", sessionID, run, id)
+	binary.Write(h, binary.BigEndian, uint32(len(slots)))
+	for _, slot := range slots {
+		binary.Write(h, binary.BigEndian, uint32(len(slot)))
+		h.Write(slot)
+	}
+	return h.Sum(nil)
+}
+
+// starts hash of commitment to vector of kind
+func commitment(kind string, sessionID uint64, run uint32, id int32) hash.Hash {
+	h := sha256.New()
+	h.Write([]byte(kind))
+	binary.Write(h, binary.BigEndian, sessionID)
+	binary.Write(h, binary.BigEndian, run)
+	binary.Write(h, binary.BigEndian, id)
+	return h
+}
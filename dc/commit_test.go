@@ -0,0 +1,35 @@
+package dc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCommit(t *testing.T) {
+	vector := []uint64{1, 2, 3}
+	slots := [][]byte{{1, 2}, {3}}
+
+	commitment := CommitDCExp(1, 1, 2, vector)
+	if !bytes.Equal(commitment, CommitDCExp(1, 1, 2, []uint64{1, 2, 3})) {
+		t.Error("For", vector, "expected", commitment, "got", CommitDCExp(1, 1, 2, vector))
+	}
+
+	// commitment binds vector to session, run and peer
+	others := [][]byte{
+		CommitDCExp(2, 1, 2, vector),
+		CommitDCExp(1, 2, 2, vector),
+		CommitDCExp(1, 1, 3, vector),
+		CommitDCExp(1, 1, 2, vector[:2]),
+		CommitDCSimple(1, 1, 2, slots),
+	}
+	for i, other := range others {
+		if bytes.Equal(other, commitment) {
+			t.Error("For", i, "expected", "distinct commitment", "got", other)
+		}
+	}
+
+	// slots are length prefixed, so their boundaries are committed to
+	if bytes.Equal(CommitDCSimple(1, 1, 2, slots), CommitDCSimple(1, 1, 2, [][]byte{{1}, {2, 3}})) {
+		t.Error("For", slots, "expected", "distinct commitment", "got", "same")
+	}
+}
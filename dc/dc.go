@@ -6,7 +6,10 @@ import (
 
 // DC - The main interface DC_NET.
 type DC interface {
-	DeriveMyDCVector(*utils.State)
-	RunDCSimple(*utils.State)
+	DeriveMyDCVector(*utils.State) error
+	ResolveDCExp(*utils.State) ([]uint64, error)
+	RunDCSimple(*utils.State) error
+	ResolveDCSimple(*utils.State) ([][]byte, error)
 	VerifyProceed(state *utils.State) bool
+	Blame(state *utils.State) []int32
 }
@@ -2,6 +2,8 @@ package dc
 
 import (
 	"github.com/dev-appmonsters/dicemix-light-client/field"
+	"github.com/dev-appmonsters/dicemix-light-client/logging"
+	"github.com/dev-appmonsters/dicemix-light-client/solver"
 	"github.com/dev-appmonsters/dicemix-light-client/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -10,15 +12,22 @@ import (
 // to expose DC-NET methods
 type dcNet struct {
 	DC
+	logger log.FieldLogger
 }
 
 // NewDCNetwork creates a new DC instance
+// logging via standard logger
 func NewDCNetwork() DC {
-	return &dcNet{}
+	return NewDCNetworkWithLogger(log.StandardLogger())
+}
+
+// NewDCNetworkWithLogger creates a new DC instance logging via logger
+func NewDCNetworkWithLogger(logger log.FieldLogger) DC {
+	return &dcNet{logger: logger}
 }
 
 // RunDCSimple - Runs DC-Simple with slot reservation
-func (d *dcNet) RunDCSimple(state *utils.State) {
+func (d *dcNet) RunDCSimple(state *utils.State) error {
 	// initaializing variables
 	slots := make([]int, state.MyMsgCount)
 	peersCount := uint32(len(state.Peers))
@@ -27,7 +36,7 @@ func (d *dcNet) RunDCSimple(state *utils.State) {
 
 	// insanity check
 	if totalMsgsCount > utils.MaxAllowedMessages {
-		log.Fatalf("Limit Exceeded: More than %d messages in tx", utils.MaxAllowedMessages)
+		return utils.NewError(utils.ErrProtocolViolation, "limit exceeded: more than %d messages in tx", utils.MaxAllowedMessages)
 	}
 
 	slots, state.MyOk = obtainSlots(state, totalMsgsCount)
@@ -44,51 +53,89 @@ func (d *dcNet) RunDCSimple(state *utils.State) {
 	// array of |totalMsgsCount| arrays of slot_size bytes, all initalized with 0
 	state.DCSimpleVector = make([][]byte, totalMsgsCount)
 
-	// reserve 20 bytes (160 bits) for each slot
+	// reserve slot of size negotiated with server
 	// to store messages of ours and peers
+	slotSize := int(state.Pool.SlotSize)
 	for j = 0; j < totalMsgsCount; j++ {
-		state.DCSimpleVector[j] = make([]byte, 20)
+		state.DCSimpleVector[j] = make([]byte, slotSize)
 	}
 
 	// store our all messages (byte encoded) in slot reserved
+	// messages are packed into slots of size we joined pool with
+	// so run with other slot size violates protocol
 	for j = 0; j < state.MyMsgCount; j++ {
-		state.DCSimpleVector[slots[j]] = utils.Base58StringToBytes(state.MyMessages[j])
+		message := utils.Base58StringToBytes(state.MyMessages[j])
+		if len(message) != slotSize {
+			return utils.NewError(utils.ErrProtocolViolation, "message of %d bytes does'nt fill slot of %d bytes", len(message), slotSize)
+		}
+		copy(state.DCSimpleVector[slots[j]], message)
 	}
 
-	log.Info("Slot's = ", state.DCSimpleVector)
+	d.logger.Info("Slot's = ", logging.Slots(state.DCSimpleVector))
 
 	// encode messages in slots
 	for i = 0; i < peersCount; i++ {
 		for j = 0; j < totalMsgsCount; j++ {
 			// xor operation - dc_simple_vector[j] = dc_simple_vector[j] + <randomness for chacha20>
-			xorBytes(state.DCSimpleVector[j], state.DCSimpleVector[j], state.Peers[i].Dicemix.GetBytes(20))
+			xorBytes(state.DCSimpleVector[j], state.DCSimpleVector[j], state.Peers[i].Dicemix.GetBytes(slotSize))
 		}
 	}
 
-	log.Info("My DC-SIMPLE vector = ", state.DCSimpleVector)
+	d.logger.Info("My DC-SIMPLE vector = ", logging.Slots(state.DCSimpleVector))
+
+	return nil
+}
+
+// ResolveDCSimple - combines our DC-SIMPLE vector with peers DC-SIMPLE vectors
+// randomness cancels out, leaving messages of all peers in their slots
+func (d *dcNet) ResolveDCSimple(state *utils.State) ([][]byte, error) {
+	totalMsgsCount := len(state.DCSimpleVector)
+	messages := make([][]byte, totalMsgsCount)
+	slotSize := int(state.Pool.SlotSize)
+
+	for j := range messages {
+		messages[j] = make([]byte, slotSize)
+		copy(messages[j], state.DCSimpleVector[j])
+	}
+
+	// messages[j] := my_dc_simple[j] (+) ∑(p.dc_simple[j])
+	for _, peer := range state.Peers {
+		if len(peer.DCSimpleVector) != totalMsgsCount {
+			return nil, utils.NewPeerError([]int32{peer.ID}, "DC-SIMPLE vector of invalid length")
+		}
+
+		for j := range messages {
+			if len(peer.DCSimpleVector[j]) != slotSize {
+				return nil, utils.NewPeerError([]int32{peer.ID}, "slot of invalid size")
+			}
+			xorBytes(messages[j], messages[j], peer.DCSimpleVector[j])
+		}
+	}
+
+	return messages, nil
 }
 
 // Run a DC-net with exponential encoding
 // generates my_dc[]
-func (d *dcNet) DeriveMyDCVector(state *utils.State) {
+func (d *dcNet) DeriveMyDCVector(state *utils.State) error {
 	// initialize variables
 	var i, j uint32
 	peersCount := uint32(len(state.Peers))
 	totalMsgsCount := messageCount(state.MyMsgCount, state.Peers)
-	state.MyDC = make([]uint64, totalMsgsCount)
 
-	// generates power sums of message_hashes
-	// my_dc[i] := my_dc[i] (+) (my_msg_hashes[j] ** (i + 1))
+	// insanity check
+	if totalMsgsCount > utils.MaxAllowedMessages {
+		return utils.NewError(utils.ErrProtocolViolation, "limit exceeded: more than %d messages in tx", utils.MaxAllowedMessages)
+	}
+
+	// generates 64 bit hash of my_message[j]
 	for j = 0; j < state.MyMsgCount; j++ {
-		// generates 64 bit hash of my_message[j]
 		state.MyMessagesHash[j] = shortHash(state.MyMessages[j])
-		var pow uint64 = 1
-		for i = 0; i < totalMsgsCount; i++ {
-			pow = power(state.MyMessagesHash[j], pow)
-			state.MyDC[i] = field.NewField(state.MyDC[i]).Add(field.NewField(pow)).Value()
-		}
 	}
 
+	// generates power sums of message_hashes
+	state.MyDC = powerSums(state.MyMessagesHash, totalMsgsCount)
+
 	// encode power sums
 	// my_dc[i] := my_dc[i] (+) (sgn(my_id - p.id) (*) p.dicemix.get_field_element())
 	for j = 0; j < peersCount; j++ {
@@ -101,8 +148,32 @@ func (d *dcNet) DeriveMyDCVector(state *utils.State) {
 		}
 	}
 
-	log.Info("My Msg Hashes = ", state.MyMessagesHash)
-	log.Info("My DC-EXP vector = ", state.MyDC)
+	d.logger.Info("My Msg Hashes = ", logging.Hashes(state.MyMessagesHash))
+	d.logger.Info("My DC-EXP vector = ", logging.Hashes(state.MyDC))
+
+	return nil
+}
+
+// ResolveDCExp - combines our DC-EXP vector with peers DC-EXP vectors
+// randomness cancels out, leaving power sums of all message hashes
+// returns roots (message hashes) solved from power sums
+func (d *dcNet) ResolveDCExp(state *utils.State) ([]uint64, error) {
+	totalMsgsCount := messageCount(state.MyMsgCount, state.Peers)
+	sums := make([]uint64, totalMsgsCount)
+	copy(sums, state.MyDC)
+
+	// dc_combined[i] := my_dc[i] (+) ∑(p.dc[i])
+	for _, peer := range state.Peers {
+		if uint32(len(peer.DCVector)) != totalMsgsCount {
+			return nil, utils.NewPeerError([]int32{peer.ID}, "DC-EXP vector of invalid length")
+		}
+
+		for i := range sums {
+			sums[i] = field.NewField(sums[i]).Add(field.NewField(peer.DCVector[i])).Value()
+		}
+	}
+
+	return solver.Solve(sums)
 }
 
 // Verify that every peer agrees to proceed
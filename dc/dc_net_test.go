@@ -0,0 +1,31 @@
+package dc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+func TestRunDCSimpleSlotSize(t *testing.T) {
+	tests := []struct {
+		size     int
+		expected error
+	}{
+		{DefaultSlotSize, nil},
+		{DefaultSlotSize - 1, utils.ErrProtocolViolation},
+		{DefaultSlotSize + 1, utils.ErrProtocolViolation},
+	}
+
+	for _, test := range tests {
+		state := &utils.State{
+			Pool:       utils.Pool{SlotSize: DefaultSlotSize},
+			MyMsgCount: 1,
+			MyMessages: []string{utils.BytesToBase58String(make([]byte, test.size))},
+		}
+
+		if err := NewDCNetwork().RunDCSimple(state); !errors.Is(err, test.expected) {
+			t.Error("For", test.size, "expected", test.expected, "got", err)
+		}
+	}
+}
@@ -20,7 +20,7 @@ func obtainSlots(state *utils.State, totalMsgsCount uint32) ([]int, bool) {
 	// Run an ordinary DC-net with slot reservations
 	for j = 0; j < state.MyMsgCount; j++ {
 		index, count := -1, 0
-		for i = 0; i < totalMsgsCount; i++ {
+		for i = 0; i < totalMsgsCount && int(i) < len(state.AllMsgHashes); i++ {
 			if state.AllMsgHashes[i] == reduce(state.MyMessagesHash[j]) {
 				index, count = int(i), int(count+1)
 			}
@@ -37,12 +37,37 @@ func obtainSlots(state *utils.State, totalMsgsCount uint32) ([]int, bool) {
 	return slots, ok
 }
 
+// checks if exactly one root equals to hash
+func uniqueRoot(allMsgHashes []uint64, hash uint64) bool {
+	count := 0
+	for _, root := range allMsgHashes {
+		if root == reduce(hash) {
+			count++
+		}
+	}
+	return count == 1
+}
+
 func shortHash(message string) uint64 {
 	// NOTE: after DC-EXP roots would contain hash reduced into field
 	// (as final result would be in field)
 	return xhashes.FNV64(message)
 }
 
+// generates power sums of message hashes
+// sums[i] := sums[i] (+) (hashes[j] ** (i + 1))
+func powerSums(hashes []uint64, totalMsgsCount uint32) []uint64 {
+	sums := make([]uint64, totalMsgsCount)
+	for _, hash := range hashes {
+		var pow uint64 = 1
+		for i := range sums {
+			pow = power(hash, pow)
+			sums[i] = field.NewField(sums[i]).Add(field.NewField(pow)).Value()
+		}
+	}
+	return sums
+}
+
 // parameter sdhould be within uint64 range
 func power(value, t uint64) uint64 {
 	return field.NewField(value).Mul(field.NewField(t)).Value()
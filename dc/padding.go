@@ -0,0 +1,190 @@
+package dc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// slot sizes which can be negotiated with server
+const (
+	// DefaultSlotSize - fits 160 bit message along with header
+	DefaultSlotSize = 24
+	// MinSlotSize - smallest slot able to carry chunk of message
+	MinSlotSize = 16
+	// MaxSlotSize - Basic sanity check to avoid weird inputs
+	MaxSlotSize = 4096
+)
+
+// every slot starts with header
+// count (1 byte) - number of chunks message is split into
+// index (1 byte) - position of chunk in message
+// length (2 bytes) - bytes of message carried by chunk
+// tag (4 bytes) - only if count > 1, prefix of SHA-256 of message
+// which links chunks of same message
+// rest of slot is padded with zeros
+const (
+	headerSize      = 4
+	chunkHeaderSize = headerSize + 4
+	maxChunks       = 255
+)
+
+// SlotCapacity - largest message which fits in single slot of slotSize bytes
+func SlotCapacity(slotSize uint32) int {
+	return int(slotSize) - headerSize
+}
+
+// MaxMessageSize - largest message which can be mixed in slots of slotSize bytes
+func MaxMessageSize(slotSize uint32) int {
+	return maxChunks * (int(slotSize) - chunkHeaderSize)
+}
+
+// Pack - encodes message in slots of slotSize bytes
+// message is split into multiple chunks if it does'nt fit in one slot
+func Pack(message []byte, slotSize uint32) ([][]byte, error) {
+	if slotSize < MinSlotSize || slotSize > MaxSlotSize {
+		return nil, fmt.Errorf("invalid slot size %d", slotSize)
+	}
+
+	size := int(slotSize)
+	if len(message) <= SlotCapacity(slotSize) {
+		slot := make([]byte, size)
+		slot[0], slot[1] = 1, 0
+		binary.BigEndian.PutUint16(slot[2:], uint16(len(message)))
+		copy(slot[headerSize:], message)
+		return [][]byte{slot}, nil
+	}
+
+	if len(message) > MaxMessageSize(slotSize) {
+		return nil, fmt.Errorf("message of %d bytes exceeds limit of %d bytes", len(message), MaxMessageSize(slotSize))
+	}
+
+	capacity := size - chunkHeaderSize
+	count := (len(message) + capacity - 1) / capacity
+	tag := sha256.Sum256(message)
+
+	slots := make([][]byte, count)
+	for i := range slots {
+		chunk := message[i*capacity:]
+		if len(chunk) > capacity {
+			chunk = chunk[:capacity]
+		}
+
+		slots[i] = make([]byte, size)
+		slots[i][0], slots[i][1] = byte(count), byte(i)
+		binary.BigEndian.PutUint16(slots[i][2:], uint16(len(chunk)))
+		copy(slots[i][headerSize:], tag[:4])
+		copy(slots[i][chunkHeaderSize:], chunk)
+	}
+	return slots, nil
+}
+
+// chunk of message decoded from slot
+type chunk struct {
+	count int
+	index int
+	tag   []byte
+	data  []byte
+}
+
+// decodes chunk from slot, padding should be zeros
+func decodeChunk(slot []byte) (chunk, error) {
+	if len(slot) < MinSlotSize {
+		return chunk{}, fmt.Errorf("slot of %d bytes", len(slot))
+	}
+
+	c := chunk{count: int(slot[0]), index: int(slot[1])}
+	if c.count == 0 || c.index >= c.count {
+		return chunk{}, fmt.Errorf("chunk %d of %d", c.index, c.count)
+	}
+
+	offset := headerSize
+	if c.count > 1 {
+		c.tag = slot[headerSize:chunkHeaderSize]
+		offset = chunkHeaderSize
+	}
+
+	length := int(binary.BigEndian.Uint16(slot[2:]))
+	if offset+length > len(slot) {
+		return chunk{}, fmt.Errorf("chunk of %d bytes exceeds slot", length)
+	}
+	if !bytes.Equal(slot[offset+length:], make([]byte, len(slot)-offset-length)) {
+		return chunk{}, fmt.Errorf("non zero padding")
+	}
+
+	c.data = slot[offset : offset+length]
+	return c, nil
+}
+
+// Unpack - decodes messages from slots resolved in DC-SIMPLE
+// chunks are joined back into messages, which are ordered by slot of their first chunk
+func Unpack(slots [][]byte) ([][]byte, error) {
+	chunks := make([]chunk, len(slots))
+	for j, slot := range slots {
+		c, err := decodeChunk(slot)
+		if err != nil {
+			return nil, fmt.Errorf("malformed slot %d: %v", j, err)
+		}
+		chunks[j] = c
+	}
+
+	// chunks of every message mapped by tag
+	groups := make(map[string][]*chunk)
+	for j := range chunks {
+		if c := &chunks[j]; c.count > 1 {
+			groups[string(c.tag)] = append(groups[string(c.tag)], c)
+		}
+	}
+
+	messages := make([][]byte, 0, len(slots))
+	joined := 0
+	for _, c := range chunks {
+		if c.count == 1 {
+			messages = append(messages, c.data)
+			continue
+		}
+		if c.index != 0 {
+			continue
+		}
+
+		message, err := join(groups[string(c.tag)], c.count)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+		joined++
+	}
+
+	// every chunk should belong to some message
+	if joined != len(groups) {
+		return nil, fmt.Errorf("chunks without first chunk of their message")
+	}
+	return messages, nil
+}
+
+// joins chunks of message in order of their index
+// message should hash to tag of its chunks
+func join(group []*chunk, count int) ([]byte, error) {
+	if len(group) != count {
+		return nil, fmt.Errorf("message split into %d chunks, found %d", count, len(group))
+	}
+
+	ordered := make([]*chunk, count)
+	for _, c := range group {
+		if c.count != count || ordered[c.index] != nil {
+			return nil, fmt.Errorf("inconsistent chunks of message")
+		}
+		ordered[c.index] = c
+	}
+
+	var message []byte
+	for _, c := range ordered {
+		message = append(message, c.data...)
+	}
+
+	if tag := sha256.Sum256(message); !bytes.Equal(tag[:4], group[0].tag) {
+		return nil, fmt.Errorf("chunks does'nt match tag of message")
+	}
+	return message, nil
+}
@@ -0,0 +1,114 @@
+package dc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func randomBytes(size int) []byte {
+	message := make([]byte, size)
+	rand.Read(message)
+	return message
+}
+
+var packTests = []struct {
+	size     int
+	slotSize uint32
+	slots    int
+}{
+	{0, DefaultSlotSize, 1},
+	{20, DefaultSlotSize, 1},
+	{21, DefaultSlotSize, 2},
+	{32, DefaultSlotSize, 2},
+	{33, DefaultSlotSize, 3},
+	{MaxMessageSize(DefaultSlotSize), DefaultSlotSize, 255},
+	{1000, MaxSlotSize, 1},
+}
+
+func TestPackUnpack(t *testing.T) {
+	for _, test := range packTests {
+		message := randomBytes(test.size)
+		slots, err := Pack(message, test.slotSize)
+		if err != nil || len(slots) != test.slots {
+			t.Fatal("For", test, "expected", test.slots, "got", len(slots), err)
+		}
+
+		for _, slot := range slots {
+			if len(slot) != int(test.slotSize) {
+				t.Error("For", test, "expected", test.slotSize, "got", len(slot))
+			}
+		}
+
+		messages, err := Unpack(slots)
+		if err != nil || len(messages) != 1 || !bytes.Equal(messages[0], message) {
+			t.Error("For", test, "expected", message, "got", messages, err)
+		}
+	}
+}
+
+func TestUnpackInterleaved(t *testing.T) {
+	first, second := randomBytes(40), randomBytes(10)
+	a, _ := Pack(first, DefaultSlotSize)
+	b, _ := Pack(second, DefaultSlotSize)
+
+	// slots are ordered by roots, not by chunk index
+	slots := [][]byte{a[2], b[0], a[0], a[1]}
+
+	messages, err := Unpack(slots)
+	if err != nil || len(messages) != 2 {
+		t.Fatal("For", "interleaved chunks", "expected", 2, "got", len(messages), err)
+	}
+	if !bytes.Equal(messages[0], second) || !bytes.Equal(messages[1], first) {
+		t.Error("For", "interleaved chunks", "expected", [][]byte{second, first}, "got", messages)
+	}
+}
+
+func TestPackInvalid(t *testing.T) {
+	tests := []struct {
+		size     int
+		slotSize uint32
+	}{
+		{10, MinSlotSize - 1},
+		{10, MaxSlotSize + 1},
+		{MaxMessageSize(DefaultSlotSize) + 1, DefaultSlotSize},
+	}
+
+	for _, test := range tests {
+		if _, err := Pack(randomBytes(test.size), test.slotSize); err == nil {
+			t.Error("For", test, "expected", "error", "got", nil)
+		}
+	}
+}
+
+func TestUnpackMalformed(t *testing.T) {
+	message := randomBytes(40)
+	slots, _ := Pack(message, DefaultSlotSize)
+	single, _ := Pack(randomBytes(4), DefaultSlotSize)
+
+	padding := append([]byte{}, single[0]...)
+	padding[DefaultSlotSize-1] = 1
+
+	length := append([]byte{}, single[0]...)
+	length[2], length[3] = 0xff, 0xff
+
+	tampered := append([]byte{}, slots[1]...)
+	tampered[chunkHeaderSize] ^= 1
+
+	tests := [][][]byte{
+		{make([]byte, DefaultSlotSize)},
+		{make([]byte, MinSlotSize-1)},
+		{padding},
+		{length},
+		{slots[0], slots[1]},
+		{slots[1], slots[2]},
+		{slots[0], tampered, slots[2]},
+		{slots[0], slots[1], slots[1]},
+	}
+
+	for i, test := range tests {
+		if _, err := Unpack(test); err == nil {
+			t.Error("For", i, "expected", "error", "got", nil)
+		}
+	}
+}
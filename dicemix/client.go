@@ -0,0 +1,317 @@
+// Package dicemix - embeddable client of DiceMix Light protocol
+package dicemix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/dev-appmonsters/dicemix-light-client/coinjoin"
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/metrics"
+	"github.com/dev-appmonsters/dicemix-light-client/server"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pool joined unless configured via WithPool
+const (
+	DefaultDenomination = 100000
+	DefaultMinPeers     = 3
+)
+
+// ErrInvalidMessages - messages can't be mixed
+var ErrInvalidMessages = errors.New("invalid messages")
+
+// Client - mixes messages with peers via DiceMix coordinator
+// Mix can be called concurrently, every call runs its own session
+type Client struct {
+	coordinator *url.URL
+	dial        transport.Dialer
+	tls         server.TLS
+	ltsk        []byte
+	ltpk        []byte
+	pool        utils.Pool
+	timeouts    server.Timeouts
+	reconnect   server.Reconnect
+	logger      log.FieldLogger
+	observer    func(server.Event)
+	metrics     *metrics.Metrics
+	fresh       func(run int) ([][]byte, error)
+	utxos       coinjoin.UTXOSource
+}
+
+// Result - outcome of successful mix
+type Result struct {
+	// Messages - anonymized messages of all peers in slot order
+	Messages [][]byte
+	// Slots - indices of our messages in Messages
+	Slots []int
+	// SessionID - session assigned by coordinator
+	SessionID uint64
+	// Runs - number of runs executed until success
+	Runs int
+	// Excluded - peers excluded from session for disrupting it
+	Excluded []int32
+	// TranscriptHash - commits to session, participants and messages
+	// equal for every honest peer of session
+	TranscriptHash []byte
+	// Transaction - coinjoin transaction signed by every peer in wire format
+	// set by CoinJoin only
+	Transaction []byte
+}
+
+// New creates a new Client instance
+// coordinator should be configured via WithCoordinator or WithTransport
+func New(options ...Option) (*Client, error) {
+	c := &Client{
+		pool:      utils.Pool{Denomination: DefaultDenomination, MinPeers: DefaultMinPeers, SlotSize: dc.DefaultSlotSize},
+		timeouts:  server.DefaultTimeouts(),
+		reconnect: server.DefaultReconnect(),
+		logger:    log.StandardLogger(),
+	}
+
+	for _, option := range options {
+		if err := option(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.coordinator == nil && c.dial == nil {
+		return nil, errors.New("coordinator is not configured")
+	}
+
+	if c.ltsk == nil {
+		var err error
+		if c.ltpk, c.ltsk, err = ecdsa.NewCurveECDSA().GenerateKeyPair(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Mix - anonymizes messages with peers in single session
+// messages of up to dc.MaxMessageSize bytes are padded into slots
+// returns once peers agree on messages or session fails
+func (c *Client) Mix(ctx context.Context, messages [][]byte) (*Result, error) {
+	return c.mix(ctx, messages, nil)
+}
+
+// CoinJoin - mixes output scripts with peers and assembles transaction
+// spending inputs of every peer into outputs of pool denomination
+// inputs are signed using their private keys once peers agree on outputs
+// outputs should be P2PKH or P2WPKH scripts
+// amounts of inputs announced by peers are trusted unless WithUTXOs is configured
+func (c *Client) CoinJoin(ctx context.Context, inputs []utils.Input, outputs [][]byte) (*Result, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: no inputs to spend", ErrInvalidMessages)
+	}
+	for i, input := range inputs {
+		if len(input.PrivateKey) == 0 {
+			return nil, fmt.Errorf("%w: input %d without private key", ErrInvalidMessages, i)
+		}
+	}
+
+	if _, err := c.packOutputs(inputs, outputs); err != nil {
+		return nil, err
+	}
+	return c.mix(ctx, outputs, inputs)
+}
+
+// packs outputs into slots if inputs can fund them
+// no change output is created, so inputs should match outputs
+func (c *Client) packOutputs(inputs []utils.Input, outputs [][]byte) ([][]byte, error) {
+	for i, output := range outputs {
+		if err := coinjoin.CheckScript(output); err != nil {
+			return nil, fmt.Errorf("%w: output %d: %v", ErrInvalidMessages, i, err)
+		}
+	}
+
+	chunks, err := Pack(outputs, c.pool.SlotSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := coinjoin.CheckFunds(inputs, uint32(len(chunks)), c.pool.SlotSize, c.pool.Denomination); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessages, err)
+	}
+	return chunks, nil
+}
+
+// runs session mixing messages, spending inputs if any
+func (c *Client) mix(ctx context.Context, messages [][]byte, inputs []utils.Input) (*Result, error) {
+	chunks, err := Pack(messages, c.pool.SlotSize)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	// messages unmasked in blame are replaced by fresh ones
+	// which are expected in final messages instead
+	if c.fresh != nil {
+		conn.SetFreshMessages(func(run uint32) ([][]byte, error) {
+			fresh, err := c.fresh(int(run))
+			if err != nil {
+				return nil, err
+			}
+
+			var chunks [][]byte
+			if inputs != nil {
+				chunks, err = c.packOutputs(inputs, fresh)
+			} else {
+				chunks, err = Pack(fresh, c.pool.SlotSize)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			messages = fresh
+			return chunks, nil
+		})
+	}
+
+	state := c.newState(chunks)
+	state.MyInputs = inputs
+	if err = conn.RegisterContext(ctx, state); err != nil {
+		return nil, err
+	}
+
+	// peers agreed on slots, which should decode into messages
+	all, err := dc.Unpack(state.AllMessages)
+	if err != nil {
+		return nil, utils.NewError(utils.ErrProtocolViolation, "%v", err)
+	}
+
+	slots, err := findSlots(all, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Messages:       all,
+		Slots:          slots,
+		SessionID:      state.Session.SessionID,
+		Runs:           int(state.Run),
+		Excluded:       append([]int32{}, state.MaliciousPeers...),
+		TranscriptHash: transcriptHash(state),
+		Transaction:    state.Transaction,
+	}, nil
+}
+
+// creates connection with coordinator configured for client
+func (c *Client) connection() (server.Server, error) {
+	var conn server.Server
+	switch {
+	case c.dial != nil:
+		conn = server.NewConnection("")
+		conn.SetDialer(c.dial)
+	case c.coordinator.Scheme == "tcp":
+		addr := c.coordinator.Host
+		conn = server.NewConnection(addr)
+		conn.SetDialer(func(ctx context.Context) (transport.Transport, error) {
+			return transport.DialTCP(ctx, addr, nil)
+		})
+	default:
+		conn = server.NewConnection(c.coordinator.Host)
+		if c.coordinator.Scheme == "wss" {
+			if err := conn.SetTLS(c.tls); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	conn.SetTimeouts(c.timeouts)
+	conn.SetReconnect(c.reconnect)
+	if c.utxos != nil {
+		conn.SetUTXOs(c.utxos)
+	}
+	conn.SetLogger(c.logger)
+	if c.metrics != nil {
+		c.metrics.Instrument(conn)
+	}
+
+	// events are reported to both metrics and observer
+	if c.observer != nil {
+		conn.OnEvent(func(event server.Event) {
+			if c.metrics != nil {
+				c.metrics.Observe(event)
+			}
+			c.observer(event)
+		})
+	}
+	return conn, nil
+}
+
+// initializes state of run mixing chunks of messages
+func (c *Client) newState(messages [][]byte) *utils.State {
+	state := &utils.State{
+		Pool:           c.pool,
+		MyMsgCount:     uint32(len(messages)),
+		MyMessages:     make([]string, len(messages)),
+		MyMessagesHash: make([]uint64, len(messages)),
+	}
+	state.Session.Ltsk, state.Session.Ltpk = c.ltsk, c.ltpk
+
+	for i, message := range messages {
+		state.MyMessages[i] = utils.BytesToBase58String(message)
+	}
+	return state
+}
+
+// Pack - validates messages and packs them into DC-SIMPLE slots of slotSize bytes
+// every slot holds single chunk, so result can be used as MyMessages of state
+// duplicates would collide in DC-EXP, so they are rejected
+func Pack(messages [][]byte, slotSize uint32) ([][]byte, error) {
+	if len(messages) == 0 || len(messages) > utils.MaxAllowedMessages {
+		return nil, fmt.Errorf("%w: %d messages, expected 1 to %d", ErrInvalidMessages, len(messages), utils.MaxAllowedMessages)
+	}
+
+	chunks := make([][]byte, 0, len(messages))
+	for i, message := range messages {
+		for j := 0; j < i; j++ {
+			if bytes.Equal(messages[j], message) {
+				return nil, fmt.Errorf("%w: message %d duplicates message %d", ErrInvalidMessages, i, j)
+			}
+		}
+
+		slots, err := dc.Pack(message, slotSize)
+		if err != nil {
+			return nil, fmt.Errorf("%w: message %d: %v", ErrInvalidMessages, i, err)
+		}
+		chunks = append(chunks, slots...)
+	}
+
+	// every chunk occupies its own slot
+	if len(chunks) > utils.MaxAllowedMessages {
+		return nil, fmt.Errorf("%w: %d slots, expected at most %d", ErrInvalidMessages, len(chunks), utils.MaxAllowedMessages)
+	}
+	return chunks, nil
+}
+
+// returns slots in which our messages were placed
+func findSlots(allMessages, messages [][]byte) ([]int, error) {
+	slots := make([]int, len(messages))
+	for i, message := range messages {
+		slots[i] = -1
+		for j, slot := range allMessages {
+			if bytes.Equal(slot, message) {
+				slots[i] = j
+				break
+			}
+		}
+
+		if slots[i] == -1 {
+			return nil, utils.NewError(utils.ErrProtocolViolation, "message %d is missing in final messages", i)
+		}
+	}
+	return slots, nil
+}
@@ -0,0 +1,339 @@
+package dicemix
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/coinjoin"
+	"github.com/dev-appmonsters/dicemix-light-client/coordinator"
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/server"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil"
+	log "github.com/sirupsen/logrus"
+)
+
+func randomMessages(n, size int) [][]byte {
+	messages := make([][]byte, n)
+	for i := range messages {
+		messages[i] = make([]byte, size)
+		rand.Read(messages[i])
+	}
+	return messages
+}
+
+func TestMix(t *testing.T) {
+	// slots larger than 64 bytes are padded with multiple blocks of stream
+	for _, slotSize := range []uint32{dc.DefaultSlotSize, 100} {
+		testMix(t, WithSlotSize(slotSize))
+	}
+
+	// every DC vector is revealed after commitments of all peers
+	testMix(t, WithCommitments())
+}
+
+func testMix(t *testing.T, options ...Option) {
+	const peers = 3
+	c := coordinator.New(coordinator.Config{Peers: peers, Timeout: 10 * time.Second})
+
+	messages := make([][][]byte, peers)
+	results := make([]*Result, peers)
+	errs := make([]error, peers)
+	events := make([][]server.Event, peers)
+
+	var wg sync.WaitGroup
+	// messages of later peers are split into multiple slots
+	for i := range messages {
+		messages[i] = randomMessages(i+1, 16*(i+1))
+
+		i := i
+		client, err := New(append([]Option{
+			WithTransport(func(ctx context.Context) (transport.Transport, error) {
+				return c.Pipe(), nil
+			}),
+			WithEvents(func(event server.Event) {
+				events[i] = append(events[i], event)
+			}),
+		}, options...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = client.Mix(context.Background(), messages[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatal("For", i, "expected", nil, "got", errs[i])
+		}
+		if len(result.Messages) != 6 || result.Runs != 1 || len(result.Excluded) != 0 {
+			t.Error("For", i, "expected", "6 messages in single run", "got", result)
+		}
+
+		// our messages are in slots reported
+		for j, slot := range result.Slots {
+			if !bytes.Equal(result.Messages[slot], messages[i][j]) {
+				t.Error("For", messages[i][j], "expected", slot, "got", result.Messages[slot])
+			}
+		}
+
+		if result.SessionID != results[0].SessionID || !bytes.Equal(result.TranscriptHash, results[0].TranscriptHash) {
+			t.Error("For", i, "expected", results[0].TranscriptHash, "got", result.TranscriptHash)
+		}
+
+		checkEvents(t, events[i], result.SessionID)
+	}
+}
+
+// checks events of successful run are reported in order
+// every event after join carries session id
+func checkEvents(t *testing.T, events []server.Event, sessionID uint64) {
+	expected := []server.EventType{
+		server.EventJoined,
+		server.EventSessionStarted,
+		server.EventKeyExchangeDone,
+		server.EventRootsReceived,
+		server.EventDCSimpleSent,
+		server.EventConfirmationSent,
+		server.EventSuccess,
+	}
+
+	if len(events) != len(expected) {
+		t.Fatal("For", "successful run", "expected", expected, "got", events)
+	}
+	for i, event := range events {
+		if event.Type != expected[i] || event.Time.IsZero() {
+			t.Error("For", i, "expected", expected[i], "got", event)
+		}
+		if event.Type != server.EventJoined && event.SessionID != sessionID {
+			t.Error("For", event.Type, "expected", sessionID, "got", event.SessionID)
+		}
+	}
+	if events[1].Peers != 3 {
+		t.Error("For", server.EventSessionStarted, "expected", 3, "got", events[1].Peers)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, ltsk, _ := ecdsa.NewCurveECDSA().GenerateKeyPair()
+
+	tests := []struct {
+		options []Option
+		valid   bool
+	}{
+		{[]Option{WithCoordinator("ws://localhost:8082")}, true},
+		{[]Option{WithCoordinator("tcp://localhost:8082"), WithSigningKey(ltsk)}, true},
+		{[]Option{WithCoordinator("wss://localhost:8082/ws"), WithPool(50000, 5)}, true},
+		{[]Option{WithCoordinator("ws://localhost:8082"), WithSlotSize(dc.MaxSlotSize)}, true},
+		{[]Option{}, false},
+		{[]Option{WithCoordinator("http://localhost:8082")}, false},
+		{[]Option{WithCoordinator("ws://localhost:8082/mix")}, false},
+		{[]Option{WithCoordinator("localhost:8082")}, false},
+		{[]Option{WithCoordinator("ws://localhost:8082"), WithSigningKey(ltsk[1:])}, false},
+		{[]Option{WithCoordinator("ws://localhost:8082"), WithPool(50000, 1)}, false},
+		{[]Option{WithCoordinator("ws://localhost:8082"), WithSlotSize(dc.MinSlotSize - 1)}, false},
+	}
+
+	for i, test := range tests {
+		if _, err := New(test.options...); (err == nil) != test.valid {
+			t.Error("For", i, "expected", test.valid, "got", err)
+		}
+	}
+}
+
+func TestMixInvalidMessages(t *testing.T) {
+	client, _ := New(WithCoordinator("ws://localhost:8082"))
+	message := randomMessages(1, dc.SlotCapacity(dc.DefaultSlotSize))[0]
+
+	tests := [][][]byte{
+		{},
+		{message, message},
+		randomMessages(1, dc.MaxMessageSize(dc.DefaultSlotSize)+1),
+		randomMessages(utils.MaxAllowedMessages+1, 1),
+		// 255 slots per message, exceeding limit of slots in session
+		randomMessages(4, dc.MaxMessageSize(dc.DefaultSlotSize)),
+	}
+
+	for _, messages := range tests {
+		if _, err := client.Mix(context.Background(), messages); !errors.Is(err, ErrInvalidMessages) {
+			t.Error("For", messages, "expected", ErrInvalidMessages, "got", err)
+		}
+	}
+}
+
+func TestMixPrivacy(t *testing.T) {
+	const peers = 3
+	c := coordinator.New(coordinator.Config{Peers: peers, Timeout: 10 * time.Second})
+
+	var buffer bytes.Buffer
+	logger := log.New()
+	logger.Out = &buffer
+
+	var secrets []string
+
+	var wg sync.WaitGroup
+	for i := 0; i < peers; i++ {
+		_, ltsk, _ := ecdsa.NewCurveECDSA().GenerateKeyPair()
+		messages := randomMessages(1, dc.SlotCapacity(dc.DefaultSlotSize))
+
+		secrets = append(secrets, fmt.Sprint(ltsk), fmt.Sprint(messages[0]), utils.BytesToBase58String(messages[0]))
+
+		client, err := New(
+			WithTransport(func(ctx context.Context) (transport.Transport, error) {
+				return c.Pipe(), nil
+			}),
+			WithSigningKey(ltsk),
+			WithLogger(logger),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Mix(context.Background(), messages); err != nil {
+				t.Error("For", "mix", "expected", nil, "got", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// logs should'nt reveal keys or messages
+	for _, secret := range secrets {
+		if strings.Contains(buffer.String(), secret) {
+			t.Error("For", "privacy mode", "expected", "secret redacted", "got", secret)
+		}
+	}
+	if !strings.Contains(buffer.String(), "[redacted key]") {
+		t.Error("For", "privacy mode", "expected", "[redacted key]", "got", buffer.String())
+	}
+}
+
+func TestCoinJoin(t *testing.T) {
+	const peers = 3
+	c := coordinator.New(coordinator.Config{Peers: peers, Timeout: 10 * time.Second})
+
+	results := make([]*Result, peers)
+	errs := make([]error, peers)
+
+	// inputs of peers are checked against outputs on chain
+	inputs := make([]utils.Input, peers)
+	chain := make(map[string]utils.Input, peers)
+	for i := range inputs {
+		inputs[i] = spendable(t, DefaultDenomination+1000)
+		chain[string(inputs[i].TxHash)] = inputs[i]
+	}
+
+	var lookups int32
+	source := func(txHash []byte, index uint32) (uint64, []byte, error) {
+		atomic.AddInt32(&lookups, 1)
+		utxo, ok := chain[string(txHash)]
+		if !ok || utxo.Index != index {
+			return 0, nil, coinjoin.ErrNoUTXO
+		}
+		return utxo.Value, utxo.PkScript, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < peers; i++ {
+		client, err := New(WithTransport(func(ctx context.Context) (transport.Transport, error) {
+			return c.Pipe(), nil
+		}), WithUTXOs(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// P2WPKH output covered by input
+		output := append([]byte{0x00, 0x14}, randomMessages(1, 20)[0]...)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = client.CoinJoin(context.Background(), []utils.Input{inputs[i]}, [][]byte{output})
+		}(i)
+	}
+	wg.Wait()
+
+	// every peer looks up inputs of others
+	if lookups != peers*(peers-1) {
+		t.Error("For", "UTXO source", "expected", peers*(peers-1), "got", lookups)
+	}
+
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatal("For", i, "expected", nil, "got", errs[i])
+		}
+		// every peer obtains same transaction signed by all peers
+		if len(result.Transaction) == 0 || !bytes.Equal(result.Transaction, results[0].Transaction) {
+			t.Error("For", i, "expected", results[0].Transaction, "got", result.Transaction)
+		}
+	}
+
+	tx, err := coinjoin.Deserialize(results[0].Transaction)
+	if err != nil || len(tx.TxIn) != peers || len(tx.TxOut) != peers {
+		t.Fatal("For", "transaction", "expected", peers, "got", tx, err)
+	}
+	for _, in := range tx.TxIn {
+		if len(in.Witness) != 2 {
+			t.Error("For", in.PreviousOutPoint, "expected", "witness", "got", in.Witness)
+		}
+	}
+}
+
+// returns P2WPKH input of value along with its key
+func spendable(t *testing.T, value uint64) utils.Input {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return utils.Input{
+		TxHash:     randomMessages(1, 32)[0],
+		Value:      value,
+		PkScript:   append([]byte{0x00, 0x14}, btcutil.Hash160(key.PubKey().SerializeCompressed())...),
+		PrivateKey: key.Serialize(),
+	}
+}
+
+func TestCoinJoinInvalidOutputs(t *testing.T) {
+	client, _ := New(WithCoordinator("ws://localhost:8082"))
+	input := spendable(t, DefaultDenomination)
+	output := append([]byte{0x00, 0x14}, make([]byte, 20)...)
+
+	tests := []struct {
+		inputs  []utils.Input
+		outputs [][]byte
+	}{
+		{nil, [][]byte{output}},
+		{[]utils.Input{input}, randomMessages(1, 22)},
+		// input without key
+		{[]utils.Input{{TxHash: input.TxHash, Value: input.Value, PkScript: input.PkScript}}, [][]byte{output}},
+		// input does'nt cover second output
+		{[]utils.Input{input}, [][]byte{output, output}},
+		// surplus would be paid as fee
+		{[]utils.Input{spendable(t, 2*DefaultDenomination)}, [][]byte{output}},
+	}
+
+	for i, test := range tests {
+		if _, err := client.CoinJoin(context.Background(), test.inputs, test.outputs); !errors.Is(err, ErrInvalidMessages) {
+			t.Error("For", i, "expected", ErrInvalidMessages, "got", err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package dicemix
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/dev-appmonsters/dicemix-light-client/coinjoin"
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/metrics"
+	"github.com/dev-appmonsters/dicemix-light-client/server"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Option - configures Client
+type Option func(*Client) error
+
+// WithCoordinator - url of coordinator to mix with
+// ws:// and wss:// connect via websocket at /ws, tcp:// via length-prefixed frames
+func WithCoordinator(rawurl string) Option {
+	return func(c *Client) error {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case u.Host == "":
+			return fmt.Errorf("coordinator url %s has no host", rawurl)
+		case u.Scheme != "ws" && u.Scheme != "wss" && u.Scheme != "tcp":
+			return fmt.Errorf("unsupported scheme %s of coordinator url", u.Scheme)
+		case u.Path != "" && u.Path != "/ws":
+			return fmt.Errorf("unsupported path %s of coordinator url", u.Path)
+		}
+
+		c.coordinator = u
+		return nil
+	}
+}
+
+// WithTransport - connects to coordinator via dial instead of url
+func WithTransport(dial transport.Dialer) Option {
+	return func(c *Client) error {
+		c.dial = dial
+		return nil
+	}
+}
+
+// WithTLS - configures wss:// connection with coordinator
+func WithTLS(config server.TLS) Option {
+	return func(c *Client) error {
+		c.tls = config
+		return nil
+	}
+}
+
+// WithSigningKey - long term secret key (LTSK) used to sign requests
+// fresh key is generated for client if not provided
+// persistent key can be loaded via keystore.Key
+func WithSigningKey(ltsk []byte) Option {
+	return func(c *Client) error {
+		ltpk, err := ecdsa.NewCurveECDSA().PublicKey(ltsk)
+		if err != nil {
+			return fmt.Errorf("invalid signing key: %v", err)
+		}
+
+		c.ltsk, c.ltpk = ltsk, ltpk
+		return nil
+	}
+}
+
+// WithPool - denomination of outputs and minimum anonymity set including us
+func WithPool(denomination uint64, minPeers uint32) Option {
+	return func(c *Client) error {
+		if minPeers < 2 {
+			return fmt.Errorf("anonymity set of %d peers is too small", minPeers)
+		}
+
+		c.pool.Denomination, c.pool.MinPeers = denomination, minPeers
+		return nil
+	}
+}
+
+// WithSlotSize - size of DC-SIMPLE slots negotiated with coordinator
+// messages which does'nt fit in single slot are split into chunks
+func WithSlotSize(size uint32) Option {
+	return func(c *Client) error {
+		if size < dc.MinSlotSize || size > dc.MaxSlotSize {
+			return fmt.Errorf("slot size %d, expected %d to %d", size, dc.MinSlotSize, dc.MaxSlotSize)
+		}
+
+		c.pool.SlotSize = size
+		return nil
+	}
+}
+
+// WithCommitments - peers commit to every DC vector before revealing it
+// so that no peer can choose its vector after seeing ours
+// client is placed only in runs agreeing on it, at cost of two more rounds
+func WithCommitments() Option {
+	return func(c *Client) error {
+		c.pool.Commit = true
+		return nil
+	}
+}
+
+// WithFreshMessages - provides messages replacing ours for run
+// once they are unmasked in blame following DC-SIMPLE, session fails without it
+// for CoinJoin they should be fresh output scripts
+func WithFreshMessages(provider func(run int) ([][]byte, error)) Option {
+	return func(c *Client) error {
+		c.fresh = provider
+		return nil
+	}
+}
+
+// WithUTXOs - checks amounts and scripts of inputs announced by peers in CoinJoin
+// against source before our inputs are signed, otherwise they are trusted
+func WithUTXOs(source coinjoin.UTXOSource) Option {
+	return func(c *Client) error {
+		c.utxos = source
+		return nil
+	}
+}
+
+// WithTimeouts - deadlines of phases and overall run
+// phases without timeout wait ResponseWait seconds
+func WithTimeouts(timeouts server.Timeouts) Option {
+	return func(c *Client) error {
+		c.timeouts = timeouts
+		return nil
+	}
+}
+
+// WithReconnect - policy to reconnect after connection with coordinator drops
+func WithReconnect(reconnect server.Reconnect) Option {
+	return func(c *Client) error {
+		c.reconnect = reconnect
+		return nil
+	}
+}
+
+// WithLogger - logs progress of runs via logger instead of standard logger
+// keys, messages and hashes are redacted unless logging.SetDebug is enabled
+func WithLogger(logger log.FieldLogger) Option {
+	return func(c *Client) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithEvents - reports progress of every run to observer
+// observer is invoked synchronously from run, so it should'nt block
+func WithEvents(observer func(server.Event)) Option {
+	return func(c *Client) error {
+		c.observer = observer
+		return nil
+	}
+}
+
+// WithMetrics - records runs of client in m
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(c *Client) error {
+		c.metrics = m
+		return nil
+	}
+}
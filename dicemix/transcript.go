@@ -0,0 +1,48 @@
+package dicemix
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+// participant of session identified by coordinator
+type participant struct {
+	id   int32
+	ltpk []byte
+}
+
+// SHA-256 of session id, participants ordered by id along with their LTPK's
+// and final messages in slot order
+// every variable length field is prefixed with its length
+func transcriptHash(state *utils.State) []byte {
+	participants := []participant{{state.Session.MyID, state.Session.Ltpk}}
+	for _, peer := range state.Peers {
+		participants = append(participants, participant{peer.ID, peer.LTPubKey})
+	}
+	sort.Slice(participants, func(i, j int) bool { return participants[i].id < participants[j].id })
+
+	hash := sha256.New()
+	binary.Write(hash, binary.BigEndian, state.Session.SessionID)
+
+	binary.Write(hash, binary.BigEndian, uint32(len(participants)))
+	for _, p := range participants {
+		binary.Write(hash, binary.BigEndian, p.id)
+		writeField(hash, p.ltpk)
+	}
+
+	binary.Write(hash, binary.BigEndian, uint32(len(state.AllMessages)))
+	for _, message := range state.AllMessages {
+		writeField(hash, message)
+	}
+
+	return hash.Sum(nil)
+}
+
+func writeField(w io.Writer, field []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(field)))
+	w.Write(field)
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	ecdh "github.com/wsddn/go-ecdh"
+	"golang.org/x/crypto/curve25519"
 )
 
 type curve25519ECDH struct {
@@ -55,6 +56,14 @@ func (e *curve25519ECDH) UnmarshalSK(privateKey []byte) (crypto.PrivateKey, bool
 	return &pri, true
 }
 
+// DerivePublicKey computes the PublicKey corresponding to our private key
+// used in blame stage to verify KESK revealed by peers
+func (e *curve25519ECDH) DerivePublicKey(privKey crypto.PrivateKey) crypto.PublicKey {
+	var pub [32]byte
+	curve25519.ScalarBaseMult(&pub, privKey.(*[32]byte))
+	return &pub
+}
+
 // GenerateSharedSecret creates shared key using our private key and others public key
 func (e *curve25519ECDH) GenerateSharedSecret(privKey crypto.PrivateKey, pubKey crypto.PublicKey) ([]byte, error) {
 	var ecdhCurve = ecdh.NewCurve25519ECDH()
@@ -11,5 +11,6 @@ type ECDH interface {
 	MarshalSK(crypto.PrivateKey) []byte
 	Unmarshal([]byte) (crypto.PublicKey, bool)
 	UnmarshalSK([]byte) (crypto.PrivateKey, bool)
+	DerivePublicKey(crypto.PrivateKey) crypto.PublicKey
 	GenerateSharedSecret(crypto.PrivateKey, crypto.PublicKey) ([]byte, error)
 }
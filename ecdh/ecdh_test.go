@@ -42,3 +42,19 @@ func TestSharedSecret(t *testing.T) {
 		}
 	}
 }
+
+func TestDerivePublicKey(t *testing.T) {
+	ecdh := NewCurve25519ECDH()
+	for i := 0; i < 5; i++ {
+		privateKey, publicKey, _ := ecdh.GenerateKeyPair()
+		derived := ecdh.DerivePublicKey(privateKey)
+
+		if !bytes.Equal(ecdh.Marshal(publicKey), ecdh.Marshal(derived)) {
+			t.Error(
+				"For", ecdh.MarshalSK(privateKey),
+				"expected", ecdh.Marshal(publicKey),
+				"got", ecdh.Marshal(derived),
+			)
+		}
+	}
+}
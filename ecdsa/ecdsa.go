@@ -3,5 +3,7 @@ package ecdsa
 // ECDSA - The main interface P256 curve.
 type ECDSA interface {
 	GenerateKeyPair() ([]byte, []byte, error)
+	PublicKey([]byte) ([]byte, error)
 	Sign([]byte, []byte) []byte
+	Verify([]byte, []byte, []byte) bool
 }
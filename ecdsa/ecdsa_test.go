@@ -39,3 +39,45 @@ func TestKeyPair(t *testing.T) {
 		}
 	}
 }
+
+func TestVerify(t *testing.T) {
+	ecdsa := NewCurveECDSA()
+	for _, pair := range signTests {
+		publicKey, privateKey, _ := ecdsa.GenerateKeyPair()
+		signature := ecdsa.Sign(privateKey, pair.data[1])
+
+		if !ecdsa.Verify(publicKey, pair.data[1], signature) {
+			t.Error(
+				"For", pair.data[1],
+				"expected valid signature",
+				"got", signature,
+			)
+		}
+
+		// signature should'nt verify for tampered message
+		tampered := append([]byte{}, pair.data[1]...)
+		tampered[0] ^= 1
+		if ecdsa.Verify(publicKey, tampered, signature) {
+			t.Error(
+				"For", tampered,
+				"expected invalid signature",
+				"got", signature,
+			)
+		}
+	}
+}
+
+func TestPublicKey(t *testing.T) {
+	ecdsa := NewCurveECDSA()
+	publicKey, privateKey, _ := ecdsa.GenerateKeyPair()
+
+	if output, err := ecdsa.PublicKey(privateKey); err != nil || !bytes.Equal(output, publicKey) {
+		t.Error("For", privateKey, "expected", publicKey, "got", output, err)
+	}
+
+	for _, invalid := range [][]byte{nil, make([]byte, 32), bytes.Repeat([]byte{0xff}, 32), privateKey[1:]} {
+		if _, err := ecdsa.PublicKey(invalid); err == nil {
+			t.Error("For", invalid, "expected", "error", "got", nil)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package ecdsa
 
 import (
+	"errors"
+	"fmt"
+	"math/big"
+
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 )
@@ -31,6 +35,22 @@ func (e *curveS256) GenerateKeyPair() ([]byte, []byte, error) {
 	return publicKeyBytes, privateKeyBytes, nil
 }
 
+// PublicKey derives compressed public key from privateKey
+// returns error if privateKey is not a valid secp256k1 scalar
+func (e *curveS256) PublicKey(privateKeyBytes []byte) ([]byte, error) {
+	if len(privateKeyBytes) != btcec.PrivKeyBytesLen {
+		return nil, fmt.Errorf("private key of %d bytes, expected %d", len(privateKeyBytes), btcec.PrivKeyBytesLen)
+	}
+
+	scalar := new(big.Int).SetBytes(privateKeyBytes)
+	if scalar.Sign() == 0 || scalar.Cmp(btcec.S256().N) >= 0 {
+		return nil, errors.New("private key out of range")
+	}
+
+	_, publicKey := btcec.PrivKeyFromBytes(btcec.S256(), privateKeyBytes)
+	return publicKey.SerializeCompressed(), nil
+}
+
 // Sign signs the message with privateKey and returns a signature. It will
 // return nil if error occurs
 func (e *curveS256) Sign(privateKeyBytes, message []byte) []byte {
@@ -51,3 +71,25 @@ func (e *curveS256) Sign(privateKeyBytes, message []byte) []byte {
 	// serialize and return the signature.
 	return signature.Serialize()
 }
+
+// Verify verifies the signature of message using publicKey. It will
+// return false if publicKey or signature are malformed
+func (e *curveS256) Verify(publicKeyBytes, message, signatureBytes []byte) bool {
+	// obtain public key object from bytes
+	publicKey, err := btcec.ParsePubKey(publicKeyBytes, btcec.S256())
+	if err != nil {
+		return false
+	}
+
+	// parse DER encoded signature
+	signature, err := btcec.ParseDERSignature(signatureBytes, btcec.S256())
+	if err != nil {
+		return false
+	}
+
+	// hash message that was sent to server
+	messageHash := chainhash.DoubleHashB(message)
+
+	// verify signature of message hash using public key
+	return signature.Verify(messageHash, publicKey)
+}
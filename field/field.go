@@ -1,7 +1,7 @@
 package field
 
 import (
-	"log"
+	"fmt"
 
 	"github.com/cznic/mathutil"
 )
@@ -27,10 +27,12 @@ func (src UInt64) reduceOnce() UInt64 {
 	return value
 }
 
+// reduceOnce always returns value less than field size
+// violation of this invariant indicates a bug, not a bad input
 func (src UInt64) reduceOnceAssert() UInt64 {
 	var res = src.reduceOnce()
 	if res >= P {
-		log.Fatalf("Error: Expected result should be less than field size %d >= %d", res, P)
+		panic(fmt.Sprintf("field: expected result should be less than field size %d >= %d", res, P))
 	}
 	return res
 }
@@ -95,6 +97,26 @@ func (src *Field) MulAssign(op2 Field) {
 	*src = src.Mul(op2)
 }
 
+// Pow raises field element to exp and reduces the resulting number in the field
+func (src Field) Pow(exp uint64) Field {
+	var res = NewField(1)
+	var base = src
+	for exp > 0 {
+		if exp&1 == 1 {
+			res = res.Mul(base)
+		}
+		base = base.Mul(base)
+		exp >>= 1
+	}
+	return res
+}
+
+// Inv returns multiplicative inverse of field element
+// using Fermat's little theorem, inverse of 0 is 0
+func (src Field) Inv() Field {
+	return src.Pow(P.Value() - 2)
+}
+
 // Value returns uint64 value from field
 func (src Field) Value() uint64 {
 	return uint64(src.Fp)
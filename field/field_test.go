@@ -38,6 +38,21 @@ var negationTests = []testpair{
 	{[]uint64{P.Value() + 5}, P.Value() - 5},
 }
 
+var powerTests = []testpair{
+	{[]uint64{3, 4}, 81},
+	{[]uint64{7, 0}, 1},
+	{[]uint64{0, 5}, 0},
+	{[]uint64{2, 61}, 1},
+	{[]uint64{1021644029483981869, P.Value() - 1}, 1},
+}
+
+var inverseTests = []testpair{
+	{[]uint64{1}, 1},
+	{[]uint64{2}, 1152921504606846976},
+	{[]uint64{P.Value() - 1}, P.Value() - 1},
+	{[]uint64{0}, 0},
+}
+
 func TestAddition(t *testing.T) {
 	for _, pair := range additionTests {
 		v := NewField(pair.data[0]).Add(NewField(pair.data[1]))
@@ -89,3 +104,29 @@ func TestNegation(t *testing.T) {
 		}
 	}
 }
+
+func TestPower(t *testing.T) {
+	for _, pair := range powerTests {
+		v := NewField(pair.data[0]).Pow(pair.data[1])
+		if v != NewField(pair.res) {
+			t.Error(
+				"For", pair.data,
+				"expected", pair.res,
+				"got", v,
+			)
+		}
+	}
+}
+
+func TestInverse(t *testing.T) {
+	for _, pair := range inverseTests {
+		v := NewField(pair.data[0]).Inv()
+		if v != NewField(pair.res) {
+			t.Error(
+				"For", pair.data,
+				"expected", pair.res,
+				"got", v,
+			)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+// encodings of messages accepted from user
+const (
+	formatHex     = "hex"
+	formatBase58  = "base58"
+	formatAddress = "address"
+)
+
+// repeatedFlag - collects values passed via repeated flag
+type repeatedFlag []string
+
+func (m *repeatedFlag) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *repeatedFlag) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+// returns params of bitcoin network addresses should belong to
+func networkParams(network string) (*chaincfg.Params, error) {
+	switch network {
+	case "mainnet":
+		return &chaincfg.MainNetParams, nil
+	case "testnet3":
+		return &chaincfg.TestNet3Params, nil
+	case "regtest":
+		return &chaincfg.RegressionNetParams, nil
+	}
+	return nil, fmt.Errorf("unknown network - %s", network)
+}
+
+// reads messages one per line, blank lines and lines starting with # are skipped
+// path "-" reads from stdin
+func readMessages(path string, stdin io.Reader) ([]string, error) {
+	reader := stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var values []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		values = append(values, line)
+	}
+	return values, scanner.Err()
+}
+
+// decodes messages given in format
+// addresses are mixed as output scripts paying to them
+func decodeMessages(values []string, format string, params *chaincfg.Params) ([][]byte, error) {
+	messages := make([][]byte, len(values))
+	for i, value := range values {
+		var err error
+		switch format {
+		case formatHex:
+			messages[i], err = hex.DecodeString(strings.TrimPrefix(value, "0x"))
+		case formatBase58:
+			messages[i], err = decodeBase58(value)
+		case formatAddress:
+			messages[i], err = decodeAddress(value, params)
+		default:
+			return nil, fmt.Errorf("unknown format - %s", format)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("message %d (%s): %v", i+1, value, err)
+		}
+		if len(messages[i]) == 0 {
+			return nil, fmt.Errorf("message %d is empty", i+1)
+		}
+	}
+	return messages, nil
+}
+
+// base58 decoding yields empty message for invalid input
+// so input should round trip
+func decodeBase58(value string) ([]byte, error) {
+	message := utils.Base58StringToBytes(value)
+	if utils.BytesToBase58String(message) != value {
+		return nil, fmt.Errorf("invalid base58 string")
+	}
+	return message, nil
+}
+
+// only P2PKH and P2WPKH addresses can receive mixed outputs
+func decodeAddress(value string, params *chaincfg.Params) ([]byte, error) {
+	address, err := btcutil.DecodeAddress(value, params)
+	if err != nil {
+		return nil, err
+	}
+	if !address.IsForNet(params) {
+		return nil, fmt.Errorf("address does'nt belong to %s", params.Name)
+	}
+
+	switch address.(type) {
+	case *btcutil.AddressPubKeyHash, *btcutil.AddressWitnessPubKeyHash:
+		return txscript.PayToAddrScript(address)
+	}
+	return nil, fmt.Errorf("unsupported address type %T", address)
+}
+
+// parses input given as txid:vout:value:pkscript:wif
+// txid in usual reversed hex, value in satoshis, pkscript in hex
+// wif - key signing input, in wallet import format
+func parseInput(value string) (utils.Input, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 5 {
+		return utils.Input{}, fmt.Errorf("input %s, expected txid:vout:value:pkscript:wif", value)
+	}
+
+	hash, err := chainhash.NewHashFromStr(parts[0])
+	if err != nil {
+		return utils.Input{}, err
+	}
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return utils.Input{}, err
+	}
+	amount, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return utils.Input{}, err
+	}
+	script, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return utils.Input{}, err
+	}
+	wif, err := btcutil.DecodeWIF(parts[4])
+	if err != nil {
+		return utils.Input{}, err
+	}
+
+	return utils.Input{
+		TxHash:     hash[:],
+		Index:      uint32(index),
+		Value:      amount,
+		PkScript:   script,
+		PrivateKey: wif.PrivKey.Serialize(),
+	}, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestDecodeMessages(t *testing.T) {
+	tests := []struct {
+		value    string
+		format   string
+		expected string
+	}{
+		{"0a0b0c", formatHex, "0a0b0c"},
+		{"0x0a0b0c", formatHex, "0a0b0c"},
+		{"2NEpo7TZRRrLZSi2U", formatBase58, "48656c6c6f20576f726c6421"},
+		// P2PKH
+		{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", formatAddress, "76a91477bff20c60e522dfaa3350c39b030a5d004e839a88ac"},
+		// P2WPKH
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", formatAddress, "0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+	}
+
+	for _, test := range tests {
+		messages, err := decodeMessages([]string{test.value}, test.format, &chaincfg.MainNetParams)
+		if err != nil || hex.EncodeToString(messages[0]) != test.expected {
+			t.Error("For", test.value, "expected", test.expected, "got", messages, err)
+		}
+	}
+}
+
+func TestDecodeInvalidMessages(t *testing.T) {
+	tests := []struct {
+		value  string
+		format string
+	}{
+		{"0a0b0", formatHex},
+		{"", formatHex},
+		{"0OIl", formatBase58},
+		// P2SH
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", formatAddress},
+		// testnet
+		{"mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", formatAddress},
+		{"0a0b0c", "base64"},
+	}
+
+	for _, test := range tests {
+		if _, err := decodeMessages([]string{test.value}, test.format, &chaincfg.MainNetParams); err == nil {
+			t.Error("For", test.value, "expected", "error", "got", nil)
+		}
+	}
+}
+
+func TestReadMessages(t *testing.T) {
+	stdin := strings.NewReader("# outputs\n0a0b\n\n  0c0d  \n")
+
+	values, err := readMessages("-", stdin)
+	if err != nil || strings.Join(values, ",") != "0a0b,0c0d" {
+		t.Error("For", "stdin", "expected", "0a0b,0c0d", "got", values, err)
+	}
+
+	if _, err := readMessages("does-not-exist", stdin); err == nil {
+		t.Error("For", "missing file", "expected", "error", "got", nil)
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	messages := [][]byte{make([]byte, 10), make([]byte, 25)}
+	messages[0][0], messages[1][0] = 1, 2
+
+	_, ltsk, _ := ecdsa.NewCurveECDSA().GenerateKeyPair()
+	state, err := initialize(messages, ltsk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// message of 25 bytes is split into 2 slots of default size
+	if state.MyMsgCount != 3 || len(state.MyMessages) != 3 || len(state.MyMessagesHash) != 3 {
+		t.Error("For", "2 messages", "expected", 3, "got", state.MyMsgCount)
+	}
+
+	if _, err := initialize([][]byte{messages[0], messages[0]}, ltsk); err == nil {
+		t.Error("For", "duplicate messages", "expected", "error", "got", nil)
+	}
+
+	if _, err := initialize(messages, ltsk[1:]); err == nil {
+		t.Error("For", "invalid signing key", "expected", "error", "got", nil)
+	}
+}
+
+func TestParseInput(t *testing.T) {
+	txid := "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+	script := "0014751e76e8199196d454941c45d1b3a323f1433bd6"
+	wif := "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ"
+
+	input, err := parseInput(txid + ":1:150000:" + script + ":" + wif)
+	if err != nil || input.Index != 1 || input.Value != 150000 || len(input.PkScript) != 22 {
+		t.Fatal("For", txid, "expected", "input", "got", input, err)
+	}
+
+	// hash is stored in internal byte order
+	if input.TxHash[0] != 0xf0 || input.TxHash[31] != 0x0f {
+		t.Error("For", txid, "expected", "reversed hash", "got", input.TxHash)
+	}
+	if hex.EncodeToString(input.PrivateKey) != "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d" {
+		t.Error("For", wif, "expected", "private key", "got", input.PrivateKey)
+	}
+
+	tests := []string{
+		txid + ":1:150000:" + script,
+		"zz:1:150000:00:" + wif,
+		txid + ":-1:150000:00:" + wif,
+		txid + ":1:0.5:00:" + wif,
+		txid + ":1:150000:0:" + wif,
+		txid + ":1:150000:00:" + wif[1:],
+	}
+
+	for _, value := range tests {
+		if _, err := parseInput(value); err == nil {
+			t.Error("For", value, "expected", "error", "got", nil)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+)
+
+// Policy - identity client signs its sessions with
+type Policy int
+
+const (
+	// PolicyFresh - new LTSK for every session
+	// peers can't link sessions of client using its LTPK
+	PolicyFresh Policy = iota
+	// PolicyPersistent - same LTSK stored in keystore for every session
+	PolicyPersistent
+)
+
+var policyNames = map[string]Policy{
+	"fresh":      PolicyFresh,
+	"persistent": PolicyPersistent,
+}
+
+// ParsePolicy - parses policy from its name, fresh or persistent
+func ParsePolicy(name string) (Policy, error) {
+	policy, ok := policyNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown identity policy %q", name)
+	}
+	return policy, nil
+}
+
+func (p Policy) String() string {
+	switch p {
+	case PolicyFresh:
+		return "fresh"
+	case PolicyPersistent:
+		return "persistent"
+	}
+	return fmt.Sprintf("policy(%d)", int(p))
+}
+
+// Key - returns LTSK to sign session with according to policy
+// fresh key is generated for PolicyFresh
+// for PolicyPersistent key is loaded from keystore at path, created on first use
+func Key(policy Policy, path string, passphrase []byte) ([]byte, error) {
+	switch policy {
+	case PolicyFresh:
+		_, ltsk, err := ecdsa.NewCurveECDSA().GenerateKeyPair()
+		return ltsk, err
+	case PolicyPersistent:
+		return Open(path, passphrase)
+	}
+	return nil, fmt.Errorf("unknown identity policy %d", int(policy))
+}
+
+// ParseKey - decodes LTSK from hex or WIF
+func ParseKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+
+	ltsk, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		wif, err := btcutil.DecodeWIF(value)
+		if err != nil {
+			return nil, fmt.Errorf("key is neither hex nor WIF: %v", err)
+		}
+		ltsk = wif.PrivKey.Serialize()
+	}
+
+	if _, err := ecdsa.NewCurveECDSA().PublicKey(ltsk); err != nil {
+		return nil, err
+	}
+	return ltsk, nil
+}
+
+// ExportHex - encodes LTSK in hex
+func ExportHex(ltsk []byte) string {
+	return hex.EncodeToString(ltsk)
+}
+
+// ExportWIF - encodes LTSK in WIF of network
+// WIF is marked compressed as LTPK is compressed
+func ExportWIF(ltsk []byte, params *chaincfg.Params) (string, error) {
+	if _, err := ecdsa.NewCurveECDSA().PublicKey(ltsk); err != nil {
+		return "", err
+	}
+
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), ltsk)
+	wif, err := btcutil.NewWIF(key, params, true)
+	if err != nil {
+		return "", err
+	}
+	return wif.String(), nil
+}
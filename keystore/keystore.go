@@ -0,0 +1,220 @@
+// Package keystore - stores long term signing key (LTSK) of client
+// in file encrypted under passphrase, so that client keeps its identity
+// across sessions if it chooses to
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// version of keystore file format
+const version = 1
+
+// scrypt parameters of new keystores
+// decryption uses parameters stored in file
+var (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// bounds of scrypt parameters accepted from file
+// so that tampered keystore can't exhaust memory on decryption
+const (
+	maxScryptN      = 1 << 20
+	maxScryptMemory = 1 << 30
+)
+
+const (
+	saltSize = 32
+	keySize  = 32
+)
+
+// ErrPassphrase - keystore can't be decrypted using passphrase provided
+var ErrPassphrase = errors.New("invalid passphrase or corrupted keystore")
+
+// keystore file, binary fields are hex encoded
+// LTPK is stored in clear to identify key without passphrase
+// and authenticated as additional data of ciphertext
+type file struct {
+	Version    int    `json:"version"`
+	PublicKey  string `json:"ltpk"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Encrypt - encrypts ltsk using AES-256-GCM under key derived
+// from passphrase via scrypt, returns contents of keystore file
+func Encrypt(ltsk, passphrase []byte) ([]byte, error) {
+	ltpk, err := ecdsa.NewCurveECDSA().PublicKey(ltsk)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(passphrase, salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(&file{
+		Version:    version,
+		PublicKey:  hex.EncodeToString(ltpk),
+		KDF:        "scrypt",
+		N:          scryptN,
+		R:          scryptR,
+		P:          scryptP,
+		Salt:       hex.EncodeToString(salt),
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(aead.Seal(nil, nonce, ltsk, ltpk)),
+	}, "", "  ")
+}
+
+// Decrypt - returns ltsk stored in contents of keystore file
+// returns ErrPassphrase if passphrase is wrong or file was tampered with
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	f := &file{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("malformed keystore: %v", err)
+	}
+	if f.Version != version || f.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported keystore version %d with kdf %s", f.Version, f.KDF)
+	}
+
+	ltpk, err1 := hex.DecodeString(f.PublicKey)
+	salt, err2 := hex.DecodeString(f.Salt)
+	nonce, err3 := hex.DecodeString(f.Nonce)
+	ciphertext, err4 := hex.DecodeString(f.Ciphertext)
+	for _, err := range []error{err1, err2, err3, err4} {
+		if err != nil {
+			return nil, fmt.Errorf("malformed keystore: %v", err)
+		}
+	}
+
+	if err := checkScrypt(f.N, f.R, f.P); err != nil {
+		return nil, fmt.Errorf("malformed keystore: %v", err)
+	}
+
+	aead, err := newAEAD(passphrase, salt, f.N, f.R, f.P)
+	if err != nil {
+		return nil, fmt.Errorf("malformed keystore: %v", err)
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("malformed keystore: nonce of %d bytes", len(nonce))
+	}
+
+	ltsk, err := aead.Open(nil, nonce, ciphertext, ltpk)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+
+	// LTPK in clear should belong to key stored
+	if derived, err := ecdsa.NewCurveECDSA().PublicKey(ltsk); err != nil || !bytes.Equal(derived, ltpk) {
+		return nil, ErrPassphrase
+	}
+	return ltsk, nil
+}
+
+// checks scrypt parameters stored in file before deriving key
+// N should be power of two up to maxScryptN, r*p below 1<<30
+// and memory of 128*r*N bytes should'nt exceed maxScryptMemory
+func checkScrypt(n, r, p int) error {
+	switch {
+	case n <= 1 || n > maxScryptN || n&(n-1) != 0:
+		return fmt.Errorf("scrypt N=%d, expected power of two up to %d", n, maxScryptN)
+	case r <= 0 || p <= 0 || r >= (1<<30)/p:
+		return fmt.Errorf("scrypt r=%d p=%d, expected r*p below %d", r, p, 1<<30)
+	case r > maxScryptMemory/(128*n):
+		return fmt.Errorf("scrypt N=%d r=%d exceed %d bytes of memory", n, r, maxScryptMemory)
+	}
+	return nil
+}
+
+// Save - writes ltsk encrypted under passphrase to new file at path
+// existing keystore is never overwritten, as identity stored in it would be lost
+func Save(path string, ltsk, passphrase []byte) error {
+	data, err := Encrypt(ltsk, passphrase)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// Load - reads ltsk from keystore at path
+func Load(path string, passphrase []byte) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(data, passphrase)
+}
+
+// Open - loads ltsk from keystore at path
+// new key is generated and saved if keystore does'nt exist yet
+func Open(path string, passphrase []byte) ([]byte, error) {
+	ltsk, err := Load(path, passphrase)
+	if !errors.Is(err, os.ErrNotExist) {
+		return ltsk, err
+	}
+
+	if _, ltsk, err = ecdsa.NewCurveECDSA().GenerateKeyPair(); err != nil {
+		return nil, err
+	}
+	if err := Save(path, ltsk, passphrase); err != nil {
+		return nil, err
+	}
+	return ltsk, nil
+}
+
+// derives key from passphrase and creates AES-256-GCM cipher using it
+func newAEAD(passphrase, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, n, r, p, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
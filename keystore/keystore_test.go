@@ -0,0 +1,218 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+var passphrase = []byte("correct horse battery staple")
+
+func init() {
+	// cheaper scrypt keeps tests fast, parameters are read back from file
+	scryptN = 1 << 10
+}
+
+func generate(t *testing.T) []byte {
+	_, ltsk, err := ecdsa.NewCurveECDSA().GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ltsk
+}
+
+// re-encodes keystore after modifying it
+func tamper(t *testing.T, data []byte, modify func(f *file)) []byte {
+	f := &file{}
+	if err := json.Unmarshal(data, f); err != nil {
+		t.Fatal(err)
+	}
+	modify(f)
+
+	tampered, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tampered
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	ltsk := generate(t)
+
+	data, err := Encrypt(ltsk, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(ExportHex(ltsk))) {
+		t.Error("For", "keystore", "expected", "encrypted key", "got", string(data))
+	}
+
+	if got, err := Decrypt(data, passphrase); err != nil || !bytes.Equal(got, ltsk) {
+		t.Error("For", "passphrase", "expected", ltsk, "got", got, err)
+	}
+
+	other := &file{}
+	encrypted, _ := Encrypt(generate(t), passphrase)
+	json.Unmarshal(encrypted, other)
+
+	tests := []struct {
+		data       []byte
+		passphrase []byte
+		expected   error
+	}{
+		{data, []byte("wrong"), ErrPassphrase},
+		// LTPK in clear is authenticated along with ciphertext
+		{tamper(t, data, func(f *file) { f.PublicKey = other.PublicKey }), passphrase, ErrPassphrase},
+		{tamper(t, data, func(f *file) { f.Ciphertext = f.Ciphertext[2:] }), passphrase, ErrPassphrase},
+		{tamper(t, data, func(f *file) { f.Version = 2 }), passphrase, nil},
+		{tamper(t, data, func(f *file) { f.Nonce = "zz" }), passphrase, nil},
+		// scrypt parameters are checked before key is derived
+		{tamper(t, data, func(f *file) { f.N = 1 << 30 }), passphrase, nil},
+		{tamper(t, data, func(f *file) { f.R, f.P = 1<<15, 1<<15 }), passphrase, nil},
+		{[]byte("{"), passphrase, nil},
+	}
+
+	for i, test := range tests {
+		_, err := Decrypt(test.data, test.passphrase)
+		if err == nil || (test.expected != nil && !errors.Is(err, test.expected)) {
+			t.Error("For", i, "expected", test.expected, "got", err)
+		}
+	}
+
+	if _, err := Encrypt(ltsk, nil); err == nil {
+		t.Error("For", "empty passphrase", "expected", "error", "got", nil)
+	}
+	if _, err := Encrypt(ltsk[1:], passphrase); err == nil {
+		t.Error("For", "invalid key", "expected", "error", "got", nil)
+	}
+}
+
+func TestCheckScrypt(t *testing.T) {
+	tests := []struct {
+		n, r, p int
+		valid   bool
+	}{
+		{1 << 10, 8, 1, true},
+		{1 << 15, 8, 1, true},
+		{1 << 20, 8, 1, true},
+		{2, 1, 16, true},
+		{1 << 21, 8, 1, false},
+		{3000, 8, 1, false},
+		{1, 8, 1, false},
+		{0, 8, 1, false},
+		{-1 << 10, 8, 1, false},
+		{1 << 10, 0, 1, false},
+		{1 << 10, 8, 0, false},
+		{1 << 10, -8, -1, false},
+		{2, 1 << 15, 1 << 15, false},
+		{1 << 20, 9, 1, false},
+	}
+
+	for _, test := range tests {
+		if err := checkScrypt(test.n, test.r, test.p); (err == nil) != test.valid {
+			t.Error("For", test.n, test.r, test.p, "expected", test.valid, "got", err)
+		}
+	}
+}
+
+func TestSaveOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dicemix.key")
+
+	// keystore is created on first use and loaded afterwards
+	ltsk, err := Open(path, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Open(path, passphrase); err != nil || !bytes.Equal(got, ltsk) {
+		t.Error("For", "existing keystore", "expected", ltsk, "got", got, err)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Error("For", path, "expected", os.FileMode(0600), "got", info, err)
+	}
+
+	// identity stored is never overwritten
+	if err := Save(path, generate(t), passphrase); err == nil {
+		t.Error("For", "existing keystore", "expected", "error", "got", nil)
+	}
+	if _, err := Open(path, []byte("wrong")); !errors.Is(err, ErrPassphrase) {
+		t.Error("For", "wrong passphrase", "expected", ErrPassphrase, "got", err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dicemix.key")
+
+	fresh1, err1 := Key(PolicyFresh, path, passphrase)
+	fresh2, err2 := Key(PolicyFresh, path, passphrase)
+	if err1 != nil || err2 != nil || bytes.Equal(fresh1, fresh2) {
+		t.Error("For", PolicyFresh, "expected", "distinct keys", "got", fresh1, fresh2)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Error("For", PolicyFresh, "expected", "no keystore", "got", err)
+	}
+
+	persistent1, err1 := Key(PolicyPersistent, path, passphrase)
+	persistent2, err2 := Key(PolicyPersistent, path, passphrase)
+	if err1 != nil || err2 != nil || !bytes.Equal(persistent1, persistent2) {
+		t.Error("For", PolicyPersistent, "expected", "same key", "got", persistent1, persistent2)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Policy
+		valid    bool
+	}{
+		{"fresh", PolicyFresh, true},
+		{"Persistent", PolicyPersistent, true},
+		{"random", 0, false},
+	}
+
+	for _, test := range tests {
+		policy, err := ParsePolicy(test.name)
+		if (err == nil) != test.valid || policy != test.expected {
+			t.Error("For", test.name, "expected", test.expected, "got", policy, err)
+		}
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	const key = "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d"
+	const wif = "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617"
+
+	tests := []string{
+		key,
+		"0x" + key,
+		" " + key + "\n",
+		wif,
+		// uncompressed WIF of same key
+		"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ",
+	}
+
+	for _, value := range tests {
+		ltsk, err := ParseKey(value)
+		if err != nil || ExportHex(ltsk) != key {
+			t.Error("For", value, "expected", key, "got", ltsk, err)
+		}
+	}
+
+	ltsk, _ := ParseKey(key)
+	if got, err := ExportWIF(ltsk, &chaincfg.MainNetParams); err != nil || got != wif {
+		t.Error("For", key, "expected", wif, "got", got, err)
+	}
+
+	for _, value := range []string{"", "0c28", wif[1:], "00000000000000000000000000000000000000000000000000000000000000"} {
+		if _, err := ParseKey(value); err == nil {
+			t.Error("For", value, "expected", "error", "got", nil)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package logging - keeps secrets which deanonymize us out of logs
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// debug mode reveals secrets in logs
+// privacy mode (default) renders them redacted
+var debug int32
+
+// SetDebug - enables debug mode, for local development only
+func SetDebug(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&debug, value)
+}
+
+// Debug - reports if debug mode is enabled
+func Debug() bool {
+	return atomic.LoadInt32(&debug) == 1
+}
+
+// Secret - value which should'nt be logged in privacy mode
+// renders as redacted with every fmt verb and in JSON
+type Secret struct {
+	kind  string
+	value interface{}
+}
+
+// Key - secret or public key linking us to our messages
+func Key(value interface{}) Secret {
+	return Secret{"key", value}
+}
+
+// Message - our messages, or messages of run
+func Message(value interface{}) Secret {
+	return Secret{"message", value}
+}
+
+// Slots - slot indices or slot contents of DC-SIMPLE vector
+func Slots(value interface{}) Secret {
+	return Secret{"slots", value}
+}
+
+// Hashes - message hashes, roots or power sums of DC-EXP
+func Hashes(value interface{}) Secret {
+	return Secret{"hashes", value}
+}
+
+// Transaction - coinjoin transaction spending our inputs, or its hash
+func Transaction(value interface{}) Secret {
+	return Secret{"transaction", value}
+}
+
+func (s Secret) String() string {
+	return fmt.Sprint(s)
+}
+
+// Format - renders value in debug mode, placeholder otherwise
+func (s Secret) Format(f fmt.State, verb rune) {
+	if Debug() {
+		fmt.Fprintf(f, fmt.FormatString(f, verb), s.value)
+		return
+	}
+	fmt.Fprintf(f, "[redacted %s]", s.kind)
+}
+
+// MarshalJSON - renders secret as string
+// so that fields of JSON formatted logs are redacted too
+func (s Secret) MarshalJSON() ([]byte, error) {
+	if Debug() {
+		return json.Marshal(s.value)
+	}
+	return json.Marshal(s.String())
+}
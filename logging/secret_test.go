@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var key = []byte{0xde, 0xad, 0xbe, 0xef}
+
+func TestRedacted(t *testing.T) {
+	tests := []struct {
+		format   string
+		args     []interface{}
+		expected string
+	}{
+		{"%v", []interface{}{Key(key)}, "[redacted key]"},
+		{"%x", []interface{}{Key(key)}, "[redacted key]"},
+		{"%s", []interface{}{Message("hello")}, "[redacted message]"},
+		{"%d", []interface{}{Hashes([]uint64{42})}, "[redacted hashes]"},
+		{"%+v", []interface{}{Slots([][]byte{key})}, "[redacted slots]"},
+		{"%s", []interface{}{Transaction("0100")}, "[redacted transaction]"},
+	}
+
+	for _, test := range tests {
+		if got := fmt.Sprintf(test.format, test.args...); got != test.expected {
+			t.Error("For", test.format, "expected", test.expected, "got", got)
+		}
+	}
+
+	if got, _ := json.Marshal(map[string]interface{}{"key": Key(key)}); string(got) != `{"key":"[redacted key]"}` {
+		t.Error("For", "json", "expected", "[redacted key]", "got", string(got))
+	}
+}
+
+func TestDebug(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+
+	tests := []struct {
+		format   string
+		args     []interface{}
+		expected string
+	}{
+		{"%v", []interface{}{Key(key)}, "[222 173 190 239]"},
+		{"%x", []interface{}{Key(key)}, "deadbeef"},
+		{"%s", []interface{}{Message("hello")}, "hello"},
+		{"%d", []interface{}{Hashes([]uint64{42})}, "[42]"},
+	}
+
+	for _, test := range tests {
+		if got := fmt.Sprintf(test.format, test.args...); got != test.expected {
+			t.Error("For", test.format, "expected", test.expected, "got", got)
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := log.New()
+	logger.Out = &buffer
+
+	logger.Info("MY Ltsk - ", Key(key))
+	logger.WithFields(log.Fields{"ltsk": Key(key)}).Info("keys")
+	logger.Formatter = &log.JSONFormatter{}
+	logger.WithFields(log.Fields{"ltsk": Key(key)}).Info("keys")
+
+	if strings.Contains(buffer.String(), "222") || strings.Contains(buffer.String(), "deadbeef") {
+		t.Error("For", "privacy mode", "expected", "[redacted key]", "got", buffer.String())
+	}
+	if strings.Count(buffer.String(), "[redacted key]") != 3 {
+		t.Error("For", "privacy mode", "expected", "[redacted key]", "got", buffer.String())
+	}
+}
@@ -1,55 +1,349 @@
 package main
 
 import (
-	"math/rand"
-	"time"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/dev-appmonsters/dicemix-light-client/coinjoin"
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/dicemix"
 	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
-
+	"github.com/dev-appmonsters/dicemix-light-client/keystore"
+	"github.com/dev-appmonsters/dicemix-light-client/logging"
+	"github.com/dev-appmonsters/dicemix-light-client/metrics"
 	"github.com/dev-appmonsters/dicemix-light-client/server"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
 	"github.com/dev-appmonsters/dicemix-light-client/utils"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// pool parameters requested while joining
+const (
+	denomination = 100000
+	minPeers     = 3
+)
+
+// environment variable holding passphrase of keystore
+const passphraseEnv = "DICEMIX_PASSPHRASE"
+
+// server configurations
+var (
+	addr = flag.String("addr", "localhost:8082", "http service address")
+	link = flag.String("transport", "ws", "transport to server - ws or tcp")
+	stat = flag.String("metrics", "", "local address to serve prometheus metrics on, disabled if empty")
+	dbg  = flag.Bool("debug", false, "log keys, messages and hashes, for local development only")
+	cmt  = flag.Bool("commit", false, "commit to DC vectors before revealing them, peers of run should agree")
+)
+
+// TLS configurations, connection is secured if any of them is set
+var (
+	secure   = flag.Bool("tls", false, "secure connection with server via TLS, using system roots unless -ca is set")
+	caFile   = flag.String("ca", "", "PEM bundle of CA's trusted to verify server certificate")
+	certFile = flag.String("cert", "", "PEM client certificate presented to server")
+	keyFile  = flag.String("key", "", "PEM key of client certificate")
+	pins     repeatedFlag
+)
+
+// messages to mix
+var (
+	input   = flag.String("in", "", "file with messages to mix, one per line, - for stdin")
+	format  = flag.String("format", formatHex, "encoding of messages - hex, base58 or address")
+	network = flag.String("network", "mainnet", "bitcoin network of addresses - mainnet, testnet3 or regtest")
+	slot    = flag.Uint("slot", dc.DefaultSlotSize, "size of DC-SIMPLE slots, longer messages are split into chunks")
+	msgs    repeatedFlag
+	utxos   repeatedFlag
+)
+
+// identity of client
+var (
+	identity  = flag.String("identity", "fresh", "long term signing key - fresh for every session or persistent from keystore")
+	keyfile   = flag.String("keystore", "dicemix.key", "file with persistent signing key encrypted under passphrase from "+passphraseEnv)
+	importKey = flag.String("import-key", "", "file with signing key in hex or WIF to store in new keystore")
+	exportKey = flag.String("export-key", "", "print signing key of keystore in hex or wif and exit")
+)
+
+func init() {
+	flag.Var(&msgs, "msg", "message to mix, can be repeated")
+	flag.Var(&utxos, "utxo", "input to spend in coinjoin as txid:vout:value:pkscript:wif, can be repeated")
+	flag.Var(&pins, "pin", "base64 encoded SHA-256 of SPKI server certificate should have, can be repeated")
+}
+
 // Entry point
 func main() {
+	flag.Parse()
+
 	// setup logger
 	formatter := &log.TextFormatter{
 		FullTimestamp: true,
 	}
 	log.SetFormatter(formatter)
 
+	// secrets are redacted from logs unless debugging
+	logging.SetDebug(*dbg)
+
+	if *exportKey != "" {
+		if err := export(*exportKey); err != nil {
+			log.Fatal("Unable to export signing key - ", err)
+		}
+		return
+	}
+
+	ltsk, err := loadKey()
+	if err != nil {
+		log.Fatal("Invalid signing key - ", err)
+	}
+
+	messages, err := loadMessages()
+	if err != nil {
+		log.Fatal("Invalid messages - ", err)
+	}
+
 	// initializes state info
-	var state = initialize()
+	state, err := initialize(messages, ltsk)
+	if err != nil {
+		log.Fatal("Invalid messages - ", err)
+	}
+
+	// messages are outputs of coinjoin if inputs are provided
+	if state.MyInputs, err = loadInputs(messages); err != nil {
+		log.Fatal("Invalid inputs - ", err)
+	}
+
+	// no change output is created, inputs exceeding outputs are paid as fee
+	if len(state.MyInputs) != 0 {
+		if err := coinjoin.CheckFunds(state.MyInputs, state.MyMsgCount, state.Pool.SlotSize, state.Pool.Denomination); err != nil {
+			log.Fatal("Invalid inputs - ", err)
+		}
+
+		// client has no UTXO source, embed dicemix package with WithUTXOs for one
+		log.Warn("Amounts of inputs announced by peers are'nt checked against chain")
+	}
 
 	log.Info("Attempt to connect to DiceMix Server")
 
-	// creating a new websocket connection with server
-	var connection = server.NewConnection()
-	connection.Register(&state)
+	// cancel run on interrupt
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	// creating a new connection with server
+	var connection = server.NewConnection(*addr)
+	if err := configureTransport(connection); err != nil {
+		log.Fatal("Invalid transport - ", err)
+	}
+
+	if *stat != "" {
+		m := metrics.New()
+		m.Instrument(connection)
+		go func() {
+			log.Info("Serving metrics on ", *stat)
+			if err := m.ListenAndServe(*stat); err != nil {
+				log.Error("Unable to serve metrics - ", err)
+			}
+		}()
+	}
+
+	if err := connection.RegisterContext(ctx, &state); err != nil {
+		log.Fatal("DiceMix run failed - ", err)
+	}
+}
+
+// configures transport of connection chosen via -transport
+// secured via TLS configured by -tls, -ca, -cert, -key and -pin flags
+func configureTransport(connection server.Server) error {
+	config := server.TLS{CAFile: *caFile, CertFile: *certFile, KeyFile: *keyFile, Pins: pins}
+	enabled := *secure || config.CAFile != "" || config.CertFile != "" || config.KeyFile != "" || len(config.Pins) != 0
+
+	switch *link {
+	case "ws":
+		if enabled {
+			return connection.SetTLS(config)
+		}
+	case "tcp":
+		var tlsConfig *tls.Config
+		if enabled {
+			host, _, err := net.SplitHostPort(*addr)
+			if err != nil {
+				return err
+			}
+			if tlsConfig, err = config.Config(host); err != nil {
+				return err
+			}
+		}
+
+		connection.SetDialer(func(ctx context.Context) (transport.Transport, error) {
+			return transport.DialTCP(ctx, *addr, tlsConfig)
+		})
+	default:
+		return fmt.Errorf("unknown transport %s", *link)
+	}
+	return nil
+}
+
+// reads messages passed via -msg flags and -in file
+func loadMessages() ([][]byte, error) {
+	values := append([]string{}, msgs...)
+	if *input != "" {
+		lines, err := readMessages(*input, os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, lines...)
+	}
+
+	if len(values) == 0 {
+		return nil, errors.New("no messages to mix, provide -msg or -in")
+	}
+
+	params, err := networkParams(*network)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMessages(values, *format, params)
+}
+
+// parses inputs passed via -utxo flags
+// every message should be output script if inputs are spent
+func loadInputs(messages [][]byte) ([]utils.Input, error) {
+	if len(utxos) == 0 {
+		return nil, nil
+	}
+
+	inputs := make([]utils.Input, len(utxos))
+	for i, utxo := range utxos {
+		var err error
+		if inputs[i], err = parseInput(utxo); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, message := range messages {
+		if err := coinjoin.CheckScript(message); err != nil {
+			return nil, fmt.Errorf("message %d: %v", i+1, err)
+		}
+	}
+	return inputs, nil
+}
+
+// returns LTSK to sign session with according to -identity
+// persistent key is loaded from -keystore, created on first use or from -import-key
+func loadKey() ([]byte, error) {
+	policy, err := keystore.ParsePolicy(*identity)
+	if err != nil {
+		return nil, err
+	}
+	if policy == keystore.PolicyFresh {
+		if *importKey != "" {
+			return nil, errors.New("imported key is'nt used unless identity is persistent")
+		}
+		return keystore.Key(policy, "", nil)
+	}
+
+	passphrase, err := keystorePassphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	if *importKey == "" {
+		return keystore.Key(policy, *keyfile, passphrase)
+	}
+
+	data, err := os.ReadFile(*importKey)
+	if err != nil {
+		return nil, err
+	}
+	ltsk, err := keystore.ParseKey(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := keystore.Save(*keyfile, ltsk, passphrase); err != nil {
+		return nil, err
+	}
+	log.Info("Imported signing key into ", *keyfile)
+	return ltsk, nil
+}
+
+// prints LTSK stored in -keystore in hex or wif
+func export(format string) error {
+	passphrase, err := keystorePassphrase()
+	if err != nil {
+		return err
+	}
+
+	ltsk, err := keystore.Load(*keyfile, passphrase)
+	if err != nil {
+		return err
+	}
+
+	var encoded string
+	switch format {
+	case formatHex:
+		encoded = keystore.ExportHex(ltsk)
+	case "wif":
+		params, err := networkParams(*network)
+		if err != nil {
+			return err
+		}
+		if encoded, err = keystore.ExportWIF(ltsk, params); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown key format %s", format)
+	}
+
+	fmt.Println(encoded)
+	return nil
 }
 
-func initialize() utils.State {
+func keystorePassphrase() ([]byte, error) {
+	passphrase := os.Getenv(passphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase of keystore should be set in %s", passphraseEnv)
+	}
+	return []byte(passphrase), nil
+}
+
+func initialize(messages [][]byte, ltsk []byte) (utils.State, error) {
 	state := utils.State{}
 
-	// NOTE: for sake of simplicity assuming user would generate random n messages
-	// 0 < n < 4
-	state.MyMsgCount = count()
+	// pool of runs to join
+	state.Pool = utils.Pool{
+		Denomination: denomination,
+		MinPeers:     minPeers,
+		SlotSize:     uint32(*slot),
+		Commit:       *cmt,
+	}
+
+	// every chunk of message occupies its own slot
+	chunks, err := dicemix.Pack(messages, state.Pool.SlotSize)
+	if err != nil {
+		return state, err
+	}
+
+	state.MyMsgCount = uint32(len(chunks))
 	state.MyMessages = make([]string, state.MyMsgCount)
 	state.MyMessagesHash = make([]uint64, state.MyMsgCount)
+	for i, chunk := range chunks {
+		state.MyMessages[i] = utils.BytesToBase58String(chunk)
+	}
 
-	// generate my LTSK, LTPK
-	ecdsa := ecdsa.NewCurveECDSA()
-	state.Session.Ltpk, state.Session.Ltsk, _ = ecdsa.GenerateKeyPair()
-
-	return state
-}
+	// my LTPK of LTSK chosen by identity policy
+	if state.Session.Ltpk, err = ecdsa.NewCurveECDSA().PublicKey(ltsk); err != nil {
+		return state, err
+	}
+	state.Session.Ltsk = ltsk
 
-// return randomly generated n
-// 0 < n < 4
-// NOTE: in actual implementation this should return count of your mesages
-func count() uint32 {
-	rand.Seed(time.Now().UnixNano())
-	return uint32(rand.Intn(3-1) + 1)
+	return state, nil
 }
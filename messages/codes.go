@@ -9,6 +9,9 @@ const (
 	C_SIMPLE_DC_VECTOR = 5
 	C_TX_CONFIRMATION  = 6
 	C_KESK_RESPONSE    = 7
+	C_RESUME_REQUEST   = 8
+	C_EXP_DC_COMMIT    = 9
+	C_SIMPLE_DC_COMMIT = 10
 )
 
 // constant Response Codes
@@ -20,4 +23,8 @@ const (
 	S_SIMPLE_DC_VECTOR = 105
 	S_TX_SUCCESSFUL    = 106
 	S_KESK_REQUEST     = 107
+	S_BLAME            = 108
+	S_RESUME_RESPONSE  = 109
+	S_EXP_DC_COMMIT    = 110
+	S_SIMPLE_DC_COMMIT = 111
 )
@@ -19,10 +19,12 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type RequestHeader struct {
-	Code                 uint32   `protobuf:"varint,1,opt,name=Code,proto3" json:"Code,omitempty"`
-	SessionId            uint64   `protobuf:"varint,2,opt,name=SessionId,proto3" json:"SessionId,omitempty"`
-	Id                   int32    `protobuf:"zigzag32,3,opt,name=Id,proto3" json:"Id,omitempty"`
-	Timestamp            string   `protobuf:"bytes,4,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
+	Code      uint32 `protobuf:"varint,1,opt,name=Code,proto3" json:"Code,omitempty"`
+	SessionId uint64 `protobuf:"varint,2,opt,name=SessionId,proto3" json:"SessionId,omitempty"`
+	Id        int32  `protobuf:"zigzag32,3,opt,name=Id,proto3" json:"Id,omitempty"`
+	Timestamp string `protobuf:"bytes,4,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
+	// run of session request is sent in, 0 before first run starts
+	Run                  uint32   `protobuf:"varint,5,opt,name=Run,proto3" json:"Run,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -32,7 +34,7 @@ func (m *RequestHeader) Reset()         { *m = RequestHeader{} }
 func (m *RequestHeader) String() string { return proto.CompactTextString(m) }
 func (*RequestHeader) ProtoMessage()    {}
 func (*RequestHeader) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{0}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{0}
 }
 func (m *RequestHeader) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RequestHeader.Unmarshal(m, b)
@@ -80,6 +82,13 @@ func (m *RequestHeader) GetTimestamp() string {
 	return ""
 }
 
+func (m *RequestHeader) GetRun() uint32 {
+	if m != nil {
+		return m.Run
+	}
+	return 0
+}
+
 // used by server for obtaining Status Code
 // from request messages sent from client
 // to parse response into suitable object
@@ -94,7 +103,7 @@ func (m *GenericRequest) Reset()         { *m = GenericRequest{} }
 func (m *GenericRequest) String() string { return proto.CompactTextString(m) }
 func (*GenericRequest) ProtoMessage()    {}
 func (*GenericRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{1}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{1}
 }
 func (m *GenericRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GenericRequest.Unmarshal(m, b)
@@ -133,7 +142,7 @@ func (m *SignedRequest) Reset()         { *m = SignedRequest{} }
 func (m *SignedRequest) String() string { return proto.CompactTextString(m) }
 func (*SignedRequest) ProtoMessage()    {}
 func (*SignedRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{2}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{2}
 }
 func (m *SignedRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SignedRequest.Unmarshal(m, b)
@@ -167,6 +176,98 @@ func (m *SignedRequest) GetSignature() []byte {
 	return nil
 }
 
+// for joining pool of DiceMix runs
+// server places us in run honouring these parameters
+// MinPeers - minimum anonymity set including us
+// SlotSize - size of DC-SIMPLE slots in bytes
+// Commit - every DC vector is committed to before it is revealed
+// Code - C_JOIN_REQUEST
+type JoinRequest struct {
+	Header               *RequestHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	Denomination         uint64         `protobuf:"varint,2,opt,name=Denomination,proto3" json:"Denomination,omitempty"`
+	NumMsgs              uint32         `protobuf:"varint,3,opt,name=NumMsgs,proto3" json:"NumMsgs,omitempty"`
+	MinPeers             uint32         `protobuf:"varint,4,opt,name=MinPeers,proto3" json:"MinPeers,omitempty"`
+	Version              uint32         `protobuf:"varint,5,opt,name=Version,proto3" json:"Version,omitempty"`
+	SlotSize             uint32         `protobuf:"varint,6,opt,name=SlotSize,proto3" json:"SlotSize,omitempty"`
+	Commit               bool           `protobuf:"varint,7,opt,name=Commit,proto3" json:"Commit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *JoinRequest) Reset()         { *m = JoinRequest{} }
+func (m *JoinRequest) String() string { return proto.CompactTextString(m) }
+func (*JoinRequest) ProtoMessage()    {}
+func (*JoinRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c1a796059245d0b2, []int{3}
+}
+func (m *JoinRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_JoinRequest.Unmarshal(m, b)
+}
+func (m *JoinRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_JoinRequest.Marshal(b, m, deterministic)
+}
+func (dst *JoinRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_JoinRequest.Merge(dst, src)
+}
+func (m *JoinRequest) XXX_Size() int {
+	return xxx_messageInfo_JoinRequest.Size(m)
+}
+func (m *JoinRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_JoinRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_JoinRequest proto.InternalMessageInfo
+
+func (m *JoinRequest) GetHeader() *RequestHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *JoinRequest) GetDenomination() uint64 {
+	if m != nil {
+		return m.Denomination
+	}
+	return 0
+}
+
+func (m *JoinRequest) GetNumMsgs() uint32 {
+	if m != nil {
+		return m.NumMsgs
+	}
+	return 0
+}
+
+func (m *JoinRequest) GetMinPeers() uint32 {
+	if m != nil {
+		return m.MinPeers
+	}
+	return 0
+}
+
+func (m *JoinRequest) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *JoinRequest) GetSlotSize() uint32 {
+	if m != nil {
+		return m.SlotSize
+	}
+	return 0
+}
+
+func (m *JoinRequest) GetCommit() bool {
+	if m != nil {
+		return m.Commit
+	}
+	return false
+}
+
 // for broadcasting our LTPK
 // to initiate DiceMix Run
 // Code - C_LTPK_REQUEST
@@ -182,7 +283,7 @@ func (m *LtpkExchangeRequest) Reset()         { *m = LtpkExchangeRequest{} }
 func (m *LtpkExchangeRequest) String() string { return proto.CompactTextString(m) }
 func (*LtpkExchangeRequest) ProtoMessage()    {}
 func (*LtpkExchangeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{3}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{4}
 }
 func (m *LtpkExchangeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_LtpkExchangeRequest.Unmarshal(m, b)
@@ -218,11 +319,13 @@ func (m *LtpkExchangeRequest) GetPublicKey() []byte {
 
 // For broadcasting our public key
 // to initiate KeyExchange
+// Inputs - outputs we spend in coinjoin transaction
 // Code - C_KEY_EXCHANGE
 type KeyExchangeRequest struct {
 	Header               *RequestHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
 	PublicKey            []byte         `protobuf:"bytes,2,opt,name=PublicKey,proto3" json:"PublicKey,omitempty"`
 	NumMsgs              uint32         `protobuf:"varint,3,opt,name=NumMsgs,proto3" json:"NumMsgs,omitempty"`
+	Inputs               []*Input       `protobuf:"bytes,4,rep,name=Inputs,proto3" json:"Inputs,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
 	XXX_unrecognized     []byte         `json:"-"`
 	XXX_sizecache        int32          `json:"-"`
@@ -232,7 +335,7 @@ func (m *KeyExchangeRequest) Reset()         { *m = KeyExchangeRequest{} }
 func (m *KeyExchangeRequest) String() string { return proto.CompactTextString(m) }
 func (*KeyExchangeRequest) ProtoMessage()    {}
 func (*KeyExchangeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{4}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{5}
 }
 func (m *KeyExchangeRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_KeyExchangeRequest.Unmarshal(m, b)
@@ -273,6 +376,13 @@ func (m *KeyExchangeRequest) GetNumMsgs() uint32 {
 	return 0
 }
 
+func (m *KeyExchangeRequest) GetInputs() []*Input {
+	if m != nil {
+		return m.Inputs
+	}
+	return nil
+}
+
 // For broadcasting our DC Exponential Vector
 // to initiate DC-EXP
 // Code - C_EXP_DC_VECTOR
@@ -288,7 +398,7 @@ func (m *DCExpRequest) Reset()         { *m = DCExpRequest{} }
 func (m *DCExpRequest) String() string { return proto.CompactTextString(m) }
 func (*DCExpRequest) ProtoMessage()    {}
 func (*DCExpRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{5}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{6}
 }
 func (m *DCExpRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DCExpRequest.Unmarshal(m, b)
@@ -322,6 +432,56 @@ func (m *DCExpRequest) GetDCExpVector() []uint64 {
 	return nil
 }
 
+// For broadcasting commitment to our DC vector
+// before revealing it, so that peers can't choose
+// their vectors after seeing ours
+// Code - C_EXP_DC_COMMIT, C_SIMPLE_DC_COMMIT
+type CommitRequest struct {
+	Header               *RequestHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	Commitment           []byte         `protobuf:"bytes,2,opt,name=Commitment,proto3" json:"Commitment,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *CommitRequest) Reset()         { *m = CommitRequest{} }
+func (m *CommitRequest) String() string { return proto.CompactTextString(m) }
+func (*CommitRequest) ProtoMessage()    {}
+func (*CommitRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c1a796059245d0b2, []int{7}
+}
+func (m *CommitRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CommitRequest.Unmarshal(m, b)
+}
+func (m *CommitRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CommitRequest.Marshal(b, m, deterministic)
+}
+func (dst *CommitRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CommitRequest.Merge(dst, src)
+}
+func (m *CommitRequest) XXX_Size() int {
+	return xxx_messageInfo_CommitRequest.Size(m)
+}
+func (m *CommitRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CommitRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CommitRequest proto.InternalMessageInfo
+
+func (m *CommitRequest) GetHeader() *RequestHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *CommitRequest) GetCommitment() []byte {
+	if m != nil {
+		return m.Commitment
+	}
+	return nil
+}
+
 // For broadcasting our DC Simple Vector
 // to initiate DC-SIMPLE
 // C_SIMPLE_DC_VECTOR
@@ -339,7 +499,7 @@ func (m *DCSimpleRequest) Reset()         { *m = DCSimpleRequest{} }
 func (m *DCSimpleRequest) String() string { return proto.CompactTextString(m) }
 func (*DCSimpleRequest) ProtoMessage()    {}
 func (*DCSimpleRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{6}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{8}
 }
 func (m *DCSimpleRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DCSimpleRequest.Unmarshal(m, b)
@@ -388,20 +548,23 @@ func (m *DCSimpleRequest) GetNextPublicKey() []byte {
 }
 
 // For broadcasting our confirmation for messages
+// Signatures - signatures of inputs we spend in coinjoin transaction,
+// set only if we confirm
 // C_TX_CONFIRMATION
 type ConfirmationRequest struct {
-	Header               *RequestHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
-	Confirmation         bool           `protobuf:"varint,2,opt,name=Confirmation,proto3" json:"Confirmation,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
-	XXX_unrecognized     []byte         `json:"-"`
-	XXX_sizecache        int32          `json:"-"`
+	Header               *RequestHeader    `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	Confirmation         bool              `protobuf:"varint,2,opt,name=Confirmation,proto3" json:"Confirmation,omitempty"`
+	Signatures           []*InputSignature `protobuf:"bytes,3,rep,name=Signatures,proto3" json:"Signatures,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *ConfirmationRequest) Reset()         { *m = ConfirmationRequest{} }
 func (m *ConfirmationRequest) String() string { return proto.CompactTextString(m) }
 func (*ConfirmationRequest) ProtoMessage()    {}
 func (*ConfirmationRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{7}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{9}
 }
 func (m *ConfirmationRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ConfirmationRequest.Unmarshal(m, b)
@@ -435,6 +598,13 @@ func (m *ConfirmationRequest) GetConfirmation() bool {
 	return false
 }
 
+func (m *ConfirmationRequest) GetSignatures() []*InputSignature {
+	if m != nil {
+		return m.Signatures
+	}
+	return nil
+}
+
 // For broadcasting our KESK
 // to initiate BLAME
 type InitiaiteKESKResponse struct {
@@ -449,7 +619,7 @@ func (m *InitiaiteKESKResponse) Reset()         { *m = InitiaiteKESKResponse{} }
 func (m *InitiaiteKESKResponse) String() string { return proto.CompactTextString(m) }
 func (*InitiaiteKESKResponse) ProtoMessage()    {}
 func (*InitiaiteKESKResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{8}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{10}
 }
 func (m *InitiaiteKESKResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InitiaiteKESKResponse.Unmarshal(m, b)
@@ -483,6 +653,57 @@ func (m *InitiaiteKESKResponse) GetPrivateKey() []byte {
 	return nil
 }
 
+// For resuming session after connection drop
+// signed using our LTSK to re-authenticate
+// Header carries SessionId and our Id in session
+// LastCode - code of last response handled by us
+// C_RESUME_REQUEST
+type ResumeRequest struct {
+	Header               *RequestHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	LastCode             uint32         `protobuf:"varint,2,opt,name=LastCode,proto3" json:"LastCode,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *ResumeRequest) Reset()         { *m = ResumeRequest{} }
+func (m *ResumeRequest) String() string { return proto.CompactTextString(m) }
+func (*ResumeRequest) ProtoMessage()    {}
+func (*ResumeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c1a796059245d0b2, []int{11}
+}
+func (m *ResumeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResumeRequest.Unmarshal(m, b)
+}
+func (m *ResumeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResumeRequest.Marshal(b, m, deterministic)
+}
+func (dst *ResumeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResumeRequest.Merge(dst, src)
+}
+func (m *ResumeRequest) XXX_Size() int {
+	return xxx_messageInfo_ResumeRequest.Size(m)
+}
+func (m *ResumeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResumeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResumeRequest proto.InternalMessageInfo
+
+func (m *ResumeRequest) GetHeader() *RequestHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *ResumeRequest) GetLastCode() uint32 {
+	if m != nil {
+		return m.LastCode
+	}
+	return 0
+}
+
 type ResponseHeader struct {
 	Code                 uint32   `protobuf:"varint,1,opt,name=Code,proto3" json:"Code,omitempty"`
 	SessionId            uint64   `protobuf:"varint,2,opt,name=SessionId,proto3" json:"SessionId,omitempty"`
@@ -498,7 +719,7 @@ func (m *ResponseHeader) Reset()         { *m = ResponseHeader{} }
 func (m *ResponseHeader) String() string { return proto.CompactTextString(m) }
 func (*ResponseHeader) ProtoMessage()    {}
 func (*ResponseHeader) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{9}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{12}
 }
 func (m *ResponseHeader) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ResponseHeader.Unmarshal(m, b)
@@ -566,7 +787,7 @@ func (m *GenericResponse) Reset()         { *m = GenericResponse{} }
 func (m *GenericResponse) String() string { return proto.CompactTextString(m) }
 func (*GenericResponse) ProtoMessage()    {}
 func (*GenericResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{10}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{13}
 }
 func (m *GenericResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GenericResponse.Unmarshal(m, b)
@@ -607,7 +828,7 @@ func (m *RegisterResponse) Reset()         { *m = RegisterResponse{} }
 func (m *RegisterResponse) String() string { return proto.CompactTextString(m) }
 func (*RegisterResponse) ProtoMessage()    {}
 func (*RegisterResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{11}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{14}
 }
 func (m *RegisterResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RegisterResponse.Unmarshal(m, b)
@@ -646,9 +867,19 @@ func (m *RegisterResponse) GetId() int32 {
 // KeyExchangeResponse - Code S_KEY_EXCHANGE
 // DCSimpleResponse - Code S_SIMPLE_DC_VECTOR
 // ConfirmationRequest - Code S_TX_CONFIRMATION
+// BlameResponse - Code S_BLAME
+// CommitResponse - Code S_EXP_DC_COMMIT, S_SIMPLE_DC_COMMIT
+// Denomination, Version, SlotSize, Commit - parameters of pool run belongs to
+// Run - number of run within session, next runs exclude peers blamed in previous one
+// set in StartDiceMix only
 type DiceMixResponse struct {
 	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
 	Peers                []*PeersInfo    `protobuf:"bytes,2,rep,name=Peers,proto3" json:"Peers,omitempty"`
+	Denomination         uint64          `protobuf:"varint,3,opt,name=Denomination,proto3" json:"Denomination,omitempty"`
+	Version              uint32          `protobuf:"varint,4,opt,name=Version,proto3" json:"Version,omitempty"`
+	SlotSize             uint32          `protobuf:"varint,5,opt,name=SlotSize,proto3" json:"SlotSize,omitempty"`
+	Run                  uint32          `protobuf:"varint,6,opt,name=Run,proto3" json:"Run,omitempty"`
+	Commit               bool            `protobuf:"varint,7,opt,name=Commit,proto3" json:"Commit,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
 	XXX_unrecognized     []byte          `json:"-"`
 	XXX_sizecache        int32           `json:"-"`
@@ -658,7 +889,7 @@ func (m *DiceMixResponse) Reset()         { *m = DiceMixResponse{} }
 func (m *DiceMixResponse) String() string { return proto.CompactTextString(m) }
 func (*DiceMixResponse) ProtoMessage()    {}
 func (*DiceMixResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{12}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{15}
 }
 func (m *DiceMixResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DiceMixResponse.Unmarshal(m, b)
@@ -692,12 +923,49 @@ func (m *DiceMixResponse) GetPeers() []*PeersInfo {
 	return nil
 }
 
+func (m *DiceMixResponse) GetDenomination() uint64 {
+	if m != nil {
+		return m.Denomination
+	}
+	return 0
+}
+
+func (m *DiceMixResponse) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *DiceMixResponse) GetSlotSize() uint32 {
+	if m != nil {
+		return m.SlotSize
+	}
+	return 0
+}
+
+func (m *DiceMixResponse) GetRun() uint32 {
+	if m != nil {
+		return m.Run
+	}
+	return 0
+}
+
+func (m *DiceMixResponse) GetCommit() bool {
+	if m != nil {
+		return m.Commit
+	}
+	return false
+}
+
 // Response against DCExpRequest
 // conatins ROOTS calculated by server using FLINT
+// and peers DC-EXP vectors to verify them
 // Code - S_EXP_DC_VECTOR
 type DCExpResponse struct {
 	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
 	Roots                []uint64        `protobuf:"varint,2,rep,packed,name=Roots,proto3" json:"Roots,omitempty"`
+	Peers                []*PeersInfo    `protobuf:"bytes,3,rep,name=Peers,proto3" json:"Peers,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
 	XXX_unrecognized     []byte          `json:"-"`
 	XXX_sizecache        int32           `json:"-"`
@@ -707,7 +975,7 @@ func (m *DCExpResponse) Reset()         { *m = DCExpResponse{} }
 func (m *DCExpResponse) String() string { return proto.CompactTextString(m) }
 func (*DCExpResponse) ProtoMessage()    {}
 func (*DCExpResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{13}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{16}
 }
 func (m *DCExpResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DCExpResponse.Unmarshal(m, b)
@@ -741,6 +1009,13 @@ func (m *DCExpResponse) GetRoots() []uint64 {
 	return nil
 }
 
+func (m *DCExpResponse) GetPeers() []*PeersInfo {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
 // Response against DCSimpleResponse
 // conatins messages resolved via DC-SIMPLE vectors
 // Code - S_SIMPLE_DC_VECTOR
@@ -757,7 +1032,7 @@ func (m *DCSimpleResponse) Reset()         { *m = DCSimpleResponse{} }
 func (m *DCSimpleResponse) String() string { return proto.CompactTextString(m) }
 func (*DCSimpleResponse) ProtoMessage()    {}
 func (*DCSimpleResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{14}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{17}
 }
 func (m *DCSimpleResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DCSimpleResponse.Unmarshal(m, b)
@@ -800,9 +1075,13 @@ func (m *DCSimpleResponse) GetPeers() []*PeersInfo {
 
 // Possible response against ConfirmationRequest
 // only when all peers send valid confirmations to server
+// Transaction - coinjoin transaction signed by every peer, if inputs were spent
+// Peers - signatures of peers to verify them
 // Code - S_TX_SUCCESSFUL
 type TXDoneResponse struct {
 	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	Transaction          []byte          `protobuf:"bytes,2,opt,name=Transaction,proto3" json:"Transaction,omitempty"`
+	Peers                []*PeersInfo    `protobuf:"bytes,3,rep,name=Peers,proto3" json:"Peers,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
 	XXX_unrecognized     []byte          `json:"-"`
 	XXX_sizecache        int32           `json:"-"`
@@ -812,7 +1091,7 @@ func (m *TXDoneResponse) Reset()         { *m = TXDoneResponse{} }
 func (m *TXDoneResponse) String() string { return proto.CompactTextString(m) }
 func (*TXDoneResponse) ProtoMessage()    {}
 func (*TXDoneResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{15}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{18}
 }
 func (m *TXDoneResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_TXDoneResponse.Unmarshal(m, b)
@@ -839,6 +1118,20 @@ func (m *TXDoneResponse) GetHeader() *ResponseHeader {
 	return nil
 }
 
+func (m *TXDoneResponse) GetTransaction() []byte {
+	if m != nil {
+		return m.Transaction
+	}
+	return nil
+}
+
+func (m *TXDoneResponse) GetPeers() []*PeersInfo {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
 // message sent by server
 // to initiate KESK
 type InitiaiteKESK struct {
@@ -852,7 +1145,7 @@ func (m *InitiaiteKESK) Reset()         { *m = InitiaiteKESK{} }
 func (m *InitiaiteKESK) String() string { return proto.CompactTextString(m) }
 func (*InitiaiteKESK) ProtoMessage()    {}
 func (*InitiaiteKESK) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{16}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{19}
 }
 func (m *InitiaiteKESK) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InitiaiteKESK.Unmarshal(m, b)
@@ -879,20 +1172,78 @@ func (m *InitiaiteKESK) GetHeader() *ResponseHeader {
 	return nil
 }
 
+// Response against ResumeRequest
+// Header.Err is set if session can't be resumed
+// Peers - status of current phase, MessageReceived is unset
+// for peers which have'nt delivered request awaited by server
+// Code - S_RESUME_RESPONSE
+type ResumeResponse struct {
+	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=Header,proto3" json:"Header,omitempty"`
+	Peers                []*PeersInfo    `protobuf:"bytes,2,rep,name=Peers,proto3" json:"Peers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ResumeResponse) Reset()         { *m = ResumeResponse{} }
+func (m *ResumeResponse) String() string { return proto.CompactTextString(m) }
+func (*ResumeResponse) ProtoMessage()    {}
+func (*ResumeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c1a796059245d0b2, []int{20}
+}
+func (m *ResumeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResumeResponse.Unmarshal(m, b)
+}
+func (m *ResumeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResumeResponse.Marshal(b, m, deterministic)
+}
+func (dst *ResumeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResumeResponse.Merge(dst, src)
+}
+func (m *ResumeResponse) XXX_Size() int {
+	return xxx_messageInfo_ResumeResponse.Size(m)
+}
+func (m *ResumeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResumeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResumeResponse proto.InternalMessageInfo
+
+func (m *ResumeResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *ResumeResponse) GetPeers() []*PeersInfo {
+	if m != nil {
+		return m.Peers
+	}
+	return nil
+}
+
 // Sub-message for DiceMixResponse
 type PeersInfo struct {
-	Id                   int32    `protobuf:"varint,1,opt,name=Id,proto3" json:"Id,omitempty"`
-	LTPublicKey          []byte   `protobuf:"bytes,2,opt,name=LTPublicKey,proto3" json:"LTPublicKey,omitempty"`
-	PublicKey            []byte   `protobuf:"bytes,3,opt,name=PublicKey,proto3" json:"PublicKey,omitempty"`
-	PrivateKey           []byte   `protobuf:"bytes,4,opt,name=PrivateKey,proto3" json:"PrivateKey,omitempty"`
-	NextPublicKey        []byte   `protobuf:"bytes,5,opt,name=NextPublicKey,proto3" json:"NextPublicKey,omitempty"`
-	NumMsgs              uint32   `protobuf:"varint,6,opt,name=NumMsgs,proto3" json:"NumMsgs,omitempty"`
-	DCVector             []uint64 `protobuf:"varint,7,rep,packed,name=DCVector,proto3" json:"DCVector,omitempty"`
-	DCSimpleVector       [][]byte `protobuf:"bytes,8,rep,name=DCSimpleVector,proto3" json:"DCSimpleVector,omitempty"`
-	OK                   bool     `protobuf:"varint,9,opt,name=OK,proto3" json:"OK,omitempty"`
-	Messages             [][]byte `protobuf:"bytes,10,rep,name=Messages,proto3" json:"Messages,omitempty"`
-	Confirmation         bool     `protobuf:"varint,11,opt,name=Confirmation,proto3" json:"Confirmation,omitempty"`
-	MessageReceived      bool     `protobuf:"varint,12,opt,name=MessageReceived,proto3" json:"MessageReceived,omitempty"`
+	Id              int32    `protobuf:"varint,1,opt,name=Id,proto3" json:"Id,omitempty"`
+	LTPublicKey     []byte   `protobuf:"bytes,2,opt,name=LTPublicKey,proto3" json:"LTPublicKey,omitempty"`
+	PublicKey       []byte   `protobuf:"bytes,3,opt,name=PublicKey,proto3" json:"PublicKey,omitempty"`
+	PrivateKey      []byte   `protobuf:"bytes,4,opt,name=PrivateKey,proto3" json:"PrivateKey,omitempty"`
+	NextPublicKey   []byte   `protobuf:"bytes,5,opt,name=NextPublicKey,proto3" json:"NextPublicKey,omitempty"`
+	NumMsgs         uint32   `protobuf:"varint,6,opt,name=NumMsgs,proto3" json:"NumMsgs,omitempty"`
+	DCVector        []uint64 `protobuf:"varint,7,rep,packed,name=DCVector,proto3" json:"DCVector,omitempty"`
+	DCSimpleVector  [][]byte `protobuf:"bytes,8,rep,name=DCSimpleVector,proto3" json:"DCSimpleVector,omitempty"`
+	OK              bool     `protobuf:"varint,9,opt,name=OK,proto3" json:"OK,omitempty"`
+	Messages        [][]byte `protobuf:"bytes,10,rep,name=Messages,proto3" json:"Messages,omitempty"`
+	Confirmation    bool     `protobuf:"varint,11,opt,name=Confirmation,proto3" json:"Confirmation,omitempty"`
+	MessageReceived bool     `protobuf:"varint,12,opt,name=MessageReceived,proto3" json:"MessageReceived,omitempty"`
+	// SignedRequest's broadcasted by peer in current stage
+	// relayed as it is, so that receivers can verify them using LTPublicKey
+	SignedRequests [][]byte          `protobuf:"bytes,13,rep,name=SignedRequests,proto3" json:"SignedRequests,omitempty"`
+	Inputs         []*Input          `protobuf:"bytes,14,rep,name=Inputs,proto3" json:"Inputs,omitempty"`
+	Signatures     []*InputSignature `protobuf:"bytes,15,rep,name=Signatures,proto3" json:"Signatures,omitempty"`
+	// commitment to DC vector peer reveals next
+	Commitment           []byte   `protobuf:"bytes,16,opt,name=Commitment,proto3" json:"Commitment,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -902,7 +1253,7 @@ func (m *PeersInfo) Reset()         { *m = PeersInfo{} }
 func (m *PeersInfo) String() string { return proto.CompactTextString(m) }
 func (*PeersInfo) ProtoMessage()    {}
 func (*PeersInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_messages_ccb5dc8f6ef7098f, []int{17}
+	return fileDescriptor_messages_c1a796059245d0b2, []int{21}
 }
 func (m *PeersInfo) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PeersInfo.Unmarshal(m, b)
@@ -1006,16 +1357,177 @@ func (m *PeersInfo) GetMessageReceived() bool {
 	return false
 }
 
+func (m *PeersInfo) GetSignedRequests() [][]byte {
+	if m != nil {
+		return m.SignedRequests
+	}
+	return nil
+}
+
+func (m *PeersInfo) GetInputs() []*Input {
+	if m != nil {
+		return m.Inputs
+	}
+	return nil
+}
+
+func (m *PeersInfo) GetSignatures() []*InputSignature {
+	if m != nil {
+		return m.Signatures
+	}
+	return nil
+}
+
+func (m *PeersInfo) GetCommitment() []byte {
+	if m != nil {
+		return m.Commitment
+	}
+	return nil
+}
+
+// Sub-message for KeyExchangeRequest and PeersInfo
+// unspent output spent by peer in coinjoin transaction
+// TxHash - hash of previous transaction in internal byte order
+type Input struct {
+	TxHash               []byte   `protobuf:"bytes,1,opt,name=TxHash,proto3" json:"TxHash,omitempty"`
+	Index                uint32   `protobuf:"varint,2,opt,name=Index,proto3" json:"Index,omitempty"`
+	Value                uint64   `protobuf:"varint,3,opt,name=Value,proto3" json:"Value,omitempty"`
+	PkScript             []byte   `protobuf:"bytes,4,opt,name=PkScript,proto3" json:"PkScript,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Input) Reset()         { *m = Input{} }
+func (m *Input) String() string { return proto.CompactTextString(m) }
+func (*Input) ProtoMessage()    {}
+func (*Input) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c1a796059245d0b2, []int{22}
+}
+func (m *Input) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Input.Unmarshal(m, b)
+}
+func (m *Input) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Input.Marshal(b, m, deterministic)
+}
+func (dst *Input) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Input.Merge(dst, src)
+}
+func (m *Input) XXX_Size() int {
+	return xxx_messageInfo_Input.Size(m)
+}
+func (m *Input) XXX_DiscardUnknown() {
+	xxx_messageInfo_Input.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Input proto.InternalMessageInfo
+
+func (m *Input) GetTxHash() []byte {
+	if m != nil {
+		return m.TxHash
+	}
+	return nil
+}
+
+func (m *Input) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *Input) GetValue() uint64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func (m *Input) GetPkScript() []byte {
+	if m != nil {
+		return m.PkScript
+	}
+	return nil
+}
+
+// Sub-message for ConfirmationRequest and PeersInfo
+// signature of input spending output TxHash:Index
+// SignatureScript for P2PKH, Witness for P2WPKH inputs
+type InputSignature struct {
+	TxHash               []byte   `protobuf:"bytes,1,opt,name=TxHash,proto3" json:"TxHash,omitempty"`
+	Index                uint32   `protobuf:"varint,2,opt,name=Index,proto3" json:"Index,omitempty"`
+	SignatureScript      []byte   `protobuf:"bytes,3,opt,name=SignatureScript,proto3" json:"SignatureScript,omitempty"`
+	Witness              [][]byte `protobuf:"bytes,4,rep,name=Witness,proto3" json:"Witness,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InputSignature) Reset()         { *m = InputSignature{} }
+func (m *InputSignature) String() string { return proto.CompactTextString(m) }
+func (*InputSignature) ProtoMessage()    {}
+func (*InputSignature) Descriptor() ([]byte, []int) {
+	return fileDescriptor_messages_c1a796059245d0b2, []int{23}
+}
+func (m *InputSignature) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InputSignature.Unmarshal(m, b)
+}
+func (m *InputSignature) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InputSignature.Marshal(b, m, deterministic)
+}
+func (dst *InputSignature) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InputSignature.Merge(dst, src)
+}
+func (m *InputSignature) XXX_Size() int {
+	return xxx_messageInfo_InputSignature.Size(m)
+}
+func (m *InputSignature) XXX_DiscardUnknown() {
+	xxx_messageInfo_InputSignature.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InputSignature proto.InternalMessageInfo
+
+func (m *InputSignature) GetTxHash() []byte {
+	if m != nil {
+		return m.TxHash
+	}
+	return nil
+}
+
+func (m *InputSignature) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *InputSignature) GetSignatureScript() []byte {
+	if m != nil {
+		return m.SignatureScript
+	}
+	return nil
+}
+
+func (m *InputSignature) GetWitness() [][]byte {
+	if m != nil {
+		return m.Witness
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*RequestHeader)(nil), "messages.RequestHeader")
 	proto.RegisterType((*GenericRequest)(nil), "messages.GenericRequest")
 	proto.RegisterType((*SignedRequest)(nil), "messages.SignedRequest")
+	proto.RegisterType((*JoinRequest)(nil), "messages.JoinRequest")
 	proto.RegisterType((*LtpkExchangeRequest)(nil), "messages.LtpkExchangeRequest")
 	proto.RegisterType((*KeyExchangeRequest)(nil), "messages.KeyExchangeRequest")
 	proto.RegisterType((*DCExpRequest)(nil), "messages.DCExpRequest")
+	proto.RegisterType((*CommitRequest)(nil), "messages.CommitRequest")
 	proto.RegisterType((*DCSimpleRequest)(nil), "messages.DCSimpleRequest")
 	proto.RegisterType((*ConfirmationRequest)(nil), "messages.ConfirmationRequest")
 	proto.RegisterType((*InitiaiteKESKResponse)(nil), "messages.InitiaiteKESKResponse")
+	proto.RegisterType((*ResumeRequest)(nil), "messages.ResumeRequest")
 	proto.RegisterType((*ResponseHeader)(nil), "messages.ResponseHeader")
 	proto.RegisterType((*GenericResponse)(nil), "messages.GenericResponse")
 	proto.RegisterType((*RegisterResponse)(nil), "messages.RegisterResponse")
@@ -1024,53 +1536,77 @@ func init() {
 	proto.RegisterType((*DCSimpleResponse)(nil), "messages.DCSimpleResponse")
 	proto.RegisterType((*TXDoneResponse)(nil), "messages.TXDoneResponse")
 	proto.RegisterType((*InitiaiteKESK)(nil), "messages.InitiaiteKESK")
+	proto.RegisterType((*ResumeResponse)(nil), "messages.ResumeResponse")
 	proto.RegisterType((*PeersInfo)(nil), "messages.PeersInfo")
-}
-
-func init() { proto.RegisterFile("messages/messages.proto", fileDescriptor_messages_ccb5dc8f6ef7098f) }
-
-var fileDescriptor_messages_ccb5dc8f6ef7098f = []byte{
-	// 668 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0x5d, 0x6f, 0x12, 0x41,
-	0x14, 0xcd, 0x2e, 0xd0, 0xc2, 0x85, 0x85, 0xba, 0xd5, 0x74, 0x63, 0x8c, 0xd9, 0x4c, 0x8c, 0xc1,
-	0x97, 0xd6, 0xd4, 0x5f, 0x50, 0x81, 0x28, 0xa1, 0x94, 0x66, 0x20, 0xea, 0xeb, 0x96, 0xbd, 0xa5,
-	0x63, 0xcb, 0x0e, 0xee, 0x0c, 0x0d, 0x7d, 0xf0, 0x17, 0xe8, 0xcf, 0xf0, 0x4f, 0xfa, 0x66, 0x76,
-	0x98, 0xfd, 0x6c, 0x13, 0xe3, 0x36, 0xbe, 0xcd, 0x3d, 0x99, 0x3d, 0xe7, 0xde, 0xbd, 0x7b, 0x0e,
-	0xc0, 0xc1, 0x12, 0x85, 0xf0, 0x16, 0x28, 0x8e, 0xe2, 0xc3, 0xe1, 0x2a, 0xe4, 0x92, 0xdb, 0xf5,
-	0xb8, 0x26, 0x1c, 0x2c, 0x8a, 0xdf, 0xd6, 0x28, 0xe4, 0x47, 0xf4, 0x7c, 0x0c, 0x6d, 0x1b, 0xaa,
-	0x3d, 0xee, 0xa3, 0x63, 0xb8, 0x46, 0xd7, 0xa2, 0xea, 0x6c, 0xbf, 0x80, 0xc6, 0x14, 0x85, 0x60,
-	0x3c, 0x18, 0xfa, 0x8e, 0xe9, 0x1a, 0xdd, 0x2a, 0x4d, 0x01, 0xbb, 0x0d, 0xe6, 0xd0, 0x77, 0x2a,
-	0xae, 0xd1, 0x7d, 0x42, 0xcd, 0xa1, 0x1f, 0xdd, 0x9e, 0xb1, 0x25, 0x0a, 0xe9, 0x2d, 0x57, 0x4e,
-	0xd5, 0x35, 0xba, 0x0d, 0x9a, 0x02, 0xe4, 0x04, 0xda, 0x1f, 0x30, 0xc0, 0x90, 0xcd, 0xb5, 0xae,
-	0x7d, 0x04, 0x3b, 0x5b, 0x6d, 0xa5, 0xd9, 0x3c, 0x3e, 0x38, 0x4c, 0xba, 0xcd, 0xb5, 0x46, 0xf5,
-	0x35, 0x32, 0x01, 0x6b, 0xca, 0x16, 0x01, 0xfa, 0x31, 0x83, 0x0b, 0x4d, 0x7d, 0xec, 0x7b, 0xd2,
-	0x53, 0x34, 0x2d, 0x9a, 0x85, 0xd4, 0x04, 0x6c, 0x11, 0x78, 0x72, 0x1d, 0xa2, 0x9a, 0xa0, 0x45,
-	0x53, 0x80, 0xf8, 0xb0, 0x7f, 0x2a, 0x57, 0xd7, 0x83, 0xcd, 0xfc, 0xca, 0x0b, 0x16, 0x58, 0xb6,
-	0xb1, 0x48, 0xe5, 0x7c, 0x7d, 0x71, 0xc3, 0xe6, 0x23, 0xbc, 0x8b, 0x55, 0x12, 0x80, 0x7c, 0x07,
-	0x7b, 0x84, 0x77, 0xff, 0x57, 0xc4, 0x76, 0x60, 0xf7, 0x6c, 0xbd, 0x1c, 0x8b, 0x85, 0x50, 0x1b,
-	0xb1, 0x68, 0x5c, 0x12, 0x0f, 0x5a, 0xfd, 0xde, 0x60, 0xb3, 0x2a, 0x2d, 0xec, 0x42, 0x53, 0x11,
-	0x7c, 0xc2, 0xb9, 0xe4, 0xa1, 0x63, 0xba, 0x95, 0x6e, 0x95, 0x66, 0x21, 0xf2, 0xcb, 0x80, 0x4e,
-	0xbf, 0x37, 0x65, 0xcb, 0xd5, 0x4d, 0xf9, 0xf9, 0x5e, 0x43, 0x3b, 0xe6, 0xc8, 0x28, 0xb5, 0x68,
-	0x01, 0x8d, 0x3e, 0xd4, 0xf1, 0xdd, 0xe4, 0x5a, 0x8d, 0x59, 0xa7, 0xea, 0x6c, 0xbf, 0x02, 0xeb,
-	0x0c, 0x37, 0x32, 0x7d, 0x3f, 0x55, 0xf5, 0x7e, 0xf2, 0x20, 0xf9, 0x0a, 0xfb, 0x3d, 0x1e, 0x5c,
-	0xb2, 0x70, 0xe9, 0x49, 0xc6, 0x83, 0xd2, 0x9d, 0x12, 0x68, 0x65, 0x79, 0xd4, 0x32, 0xea, 0x34,
-	0x87, 0x91, 0x2b, 0x78, 0x36, 0x0c, 0x98, 0x64, 0x1e, 0x93, 0x38, 0x1a, 0x4c, 0x47, 0x14, 0xc5,
-	0x8a, 0x07, 0x02, 0xff, 0x5d, 0xed, 0x25, 0xc0, 0x79, 0xc8, 0x6e, 0x3d, 0x89, 0xe9, 0xe2, 0x33,
-	0x08, 0xf9, 0x69, 0x40, 0x3b, 0x66, 0x2f, 0xed, 0xe5, 0x9c, 0x77, 0x2b, 0x05, 0xef, 0x46, 0x1f,
-	0xd7, 0x78, 0xdb, 0xa4, 0xf6, 0x75, 0x5c, 0xda, 0x7b, 0x50, 0x19, 0x84, 0xa1, 0x53, 0x53, 0x68,
-	0x74, 0x24, 0x3d, 0xe8, 0x24, 0x3e, 0xd7, 0x23, 0xbf, 0x2d, 0x8c, 0xec, 0x64, 0x47, 0xce, 0x36,
-	0x9e, 0x38, 0x7d, 0x06, 0x7b, 0x14, 0x17, 0x4c, 0x48, 0x0c, 0xcb, 0xb3, 0xe8, 0x80, 0x32, 0xe3,
-	0x80, 0x22, 0x01, 0x74, 0xfa, 0x6c, 0x8e, 0x63, 0xb6, 0x79, 0x04, 0xe9, 0x1b, 0xa8, 0x9d, 0x23,
-	0x86, 0x42, 0x7d, 0x9d, 0xcd, 0xe3, 0xfd, 0xf4, 0x01, 0x05, 0x0f, 0x83, 0x4b, 0x4e, 0xb7, 0x37,
-	0xc8, 0x67, 0xb0, 0xb4, 0xf3, 0x4a, 0xab, 0x3d, 0x85, 0x1a, 0xe5, 0x5c, 0x0a, 0xed, 0xba, 0x6d,
-	0x41, 0x7e, 0x18, 0xb0, 0x97, 0xfa, 0xad, 0x34, 0xf9, 0x73, 0xa8, 0xeb, 0x3d, 0x0a, 0xed, 0xb5,
-	0xa4, 0x4e, 0xc7, 0xac, 0xfc, 0x75, 0xcc, 0xf7, 0xd0, 0x9e, 0x7d, 0xe9, 0xf3, 0xe0, 0x11, 0xad,
-	0x90, 0x13, 0xb0, 0x72, 0x76, 0x29, 0x41, 0xf1, 0xdb, 0x84, 0x46, 0xd2, 0x9b, 0xde, 0x7d, 0xf4,
-	0x6c, 0x4d, 0xfd, 0x38, 0xb9, 0xd0, 0x3c, 0x9d, 0x15, 0xf3, 0x33, 0x0b, 0xe5, 0xf3, 0xb5, 0x52,
-	0xcc, 0xd7, 0xbc, 0x0b, 0xab, 0x45, 0x17, 0xde, 0x4f, 0xa0, 0xda, 0x03, 0x09, 0x94, 0x4d, 0xe9,
-	0x9d, 0x5c, 0x4a, 0x47, 0xbb, 0xe8, 0xf7, 0x74, 0xee, 0xed, 0xaa, 0x5d, 0x27, 0xf5, 0x03, 0xc9,
-	0x58, 0x7f, 0x30, 0x19, 0xdb, 0x60, 0x4e, 0x46, 0x4e, 0x43, 0xa5, 0x91, 0x39, 0x19, 0xe5, 0xf6,
-	0x0b, 0x85, 0xfd, 0x16, 0x33, 0xac, 0x79, 0x3f, 0xc3, 0xec, 0x2e, 0x74, 0xf4, 0x7d, 0x8a, 0x73,
-	0x64, 0xb7, 0xe8, 0x3b, 0x2d, 0x75, 0xad, 0x08, 0x5f, 0xec, 0xa8, 0xbf, 0x17, 0xef, 0xfe, 0x04,
-	0x00, 0x00, 0xff, 0xff, 0x18, 0x5d, 0xe2, 0xcc, 0x79, 0x08, 0x00, 0x00,
+	proto.RegisterType((*Input)(nil), "messages.Input")
+	proto.RegisterType((*InputSignature)(nil), "messages.InputSignature")
+}
+
+func init() { proto.RegisterFile("messages/messages.proto", fileDescriptor_messages_c1a796059245d0b2) }
+
+var fileDescriptor_messages_c1a796059245d0b2 = []byte{
+	// 996 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x57, 0xdd, 0x8e, 0xdb, 0x44,
+	0x14, 0x96, 0xf3, 0xb7, 0xc9, 0x49, 0xec, 0x2c, 0x5e, 0xa0, 0x56, 0x55, 0x21, 0xcb, 0x42, 0x60,
+	0x6e, 0x5a, 0x54, 0x6e, 0xb8, 0xad, 0x92, 0x15, 0x0d, 0xd9, 0xed, 0xae, 0x26, 0xd1, 0xc2, 0x05,
+	0x17, 0xb8, 0xf1, 0x69, 0x76, 0xb4, 0xeb, 0x71, 0xf0, 0x4c, 0xaa, 0x2c, 0x77, 0x70, 0x0b, 0x12,
+	0x2f, 0x51, 0xf1, 0x68, 0x3c, 0x00, 0x4f, 0x80, 0x66, 0x3c, 0xfe, 0xdd, 0xb4, 0xb0, 0x5e, 0xd1,
+	0xbb, 0x39, 0x5f, 0xe6, 0xe7, 0x9b, 0xe3, 0x33, 0xdf, 0x77, 0x02, 0x0f, 0x22, 0xe4, 0x3c, 0x58,
+	0x23, 0x7f, 0x92, 0x0d, 0x1e, 0x6f, 0x92, 0x58, 0xc4, 0x76, 0x3f, 0x8b, 0xbd, 0x5f, 0x0c, 0x30,
+	0x09, 0xfe, 0xb4, 0x45, 0x2e, 0x9e, 0x63, 0x10, 0x62, 0x62, 0xdb, 0xd0, 0x99, 0xc4, 0x21, 0x3a,
+	0x86, 0x6b, 0xf8, 0x26, 0x51, 0x63, 0xfb, 0x11, 0x0c, 0x16, 0xc8, 0x39, 0x8d, 0xd9, 0x2c, 0x74,
+	0x5a, 0xae, 0xe1, 0x77, 0x48, 0x01, 0xd8, 0x16, 0xb4, 0x66, 0xa1, 0xd3, 0x76, 0x0d, 0xff, 0x03,
+	0xd2, 0x9a, 0x85, 0x72, 0xf6, 0x92, 0x46, 0xc8, 0x45, 0x10, 0x6d, 0x9c, 0x8e, 0x6b, 0xf8, 0x03,
+	0x52, 0x00, 0xf6, 0x21, 0xb4, 0xc9, 0x96, 0x39, 0x5d, 0xb5, 0xbd, 0x1c, 0x7a, 0xcf, 0xc0, 0xfa,
+	0x06, 0x19, 0x26, 0x74, 0xa5, 0x99, 0xd8, 0x4f, 0xa0, 0x97, 0xb2, 0x51, 0x2c, 0x86, 0x4f, 0x1f,
+	0x3c, 0xce, 0x2f, 0x50, 0x21, 0x4b, 0xf4, 0x34, 0xef, 0x0c, 0xcc, 0x05, 0x5d, 0x33, 0x0c, 0xb3,
+	0x1d, 0x5c, 0x18, 0xea, 0xe1, 0x34, 0x10, 0x81, 0xda, 0x66, 0x44, 0xca, 0x90, 0xba, 0x13, 0x5d,
+	0xb3, 0x40, 0x6c, 0x13, 0x54, 0x77, 0x1a, 0x91, 0x02, 0xf0, 0xfe, 0x32, 0x60, 0xf8, 0x6d, 0x4c,
+	0x59, 0x53, 0x46, 0xb6, 0x07, 0xa3, 0x29, 0xb2, 0x38, 0xa2, 0x2c, 0x10, 0x34, 0x66, 0x3a, 0x6b,
+	0x15, 0xcc, 0x76, 0xe0, 0xe0, 0xc5, 0x36, 0x3a, 0xe5, 0x6b, 0xae, 0xb2, 0x67, 0x92, 0x2c, 0xb4,
+	0x1f, 0x42, 0xff, 0x94, 0xb2, 0x73, 0xc4, 0x84, 0xab, 0x0c, 0x9a, 0x24, 0x8f, 0xe5, 0xaa, 0x0b,
+	0x4c, 0x64, 0xee, 0x75, 0x12, 0xb3, 0x50, 0xae, 0x5a, 0x5c, 0xc7, 0x62, 0x41, 0x7f, 0x46, 0xa7,
+	0x97, 0xae, 0xca, 0x62, 0xfb, 0x63, 0xe8, 0x4d, 0xe2, 0x28, 0xa2, 0xc2, 0x39, 0x70, 0x0d, 0xbf,
+	0x4f, 0x74, 0xe4, 0x85, 0x70, 0x74, 0x22, 0x36, 0x57, 0xc7, 0xbb, 0xd5, 0x65, 0xc0, 0xd6, 0xd8,
+	0xf8, 0xbe, 0x8f, 0x60, 0x70, 0xbe, 0x7d, 0x79, 0x4d, 0x57, 0x73, 0xbc, 0xc9, 0xd2, 0x99, 0x03,
+	0xde, 0x9f, 0x06, 0xd8, 0x73, 0xbc, 0xf9, 0x7f, 0x4f, 0x79, 0x47, 0x3e, 0x3f, 0x87, 0xde, 0x8c,
+	0x6d, 0xb6, 0x42, 0x66, 0xb3, 0xed, 0x0f, 0x9f, 0x8e, 0x8b, 0x83, 0x14, 0x4e, 0xf4, 0xcf, 0x5e,
+	0x00, 0xa3, 0xe9, 0xe4, 0x78, 0xb7, 0x69, 0xcc, 0xd0, 0x85, 0xa1, 0xda, 0xe0, 0x02, 0x57, 0x22,
+	0x4e, 0x9c, 0x96, 0xdb, 0xf6, 0x3b, 0xa4, 0x0c, 0x79, 0x3f, 0x82, 0x99, 0xe6, 0xbe, 0xf1, 0x19,
+	0x9f, 0x00, 0xa4, 0x3b, 0x44, 0xc8, 0x84, 0x4e, 0x43, 0x09, 0xf1, 0xde, 0x18, 0x30, 0x9e, 0x4e,
+	0x16, 0x34, 0xda, 0x5c, 0x37, 0x4f, 0xf5, 0x67, 0x60, 0x65, 0x7b, 0x94, 0xee, 0x32, 0x22, 0x35,
+	0x54, 0xea, 0xc5, 0xe9, 0xcd, 0xd9, 0x95, 0xca, 0x78, 0x9f, 0xa8, 0xb1, 0xfd, 0x29, 0x98, 0x2f,
+	0x70, 0x27, 0x8a, 0x4f, 0xd5, 0x51, 0x1c, 0xab, 0xa0, 0xa4, 0x79, 0x34, 0x89, 0xd9, 0x2b, 0x9a,
+	0x44, 0xea, 0x3d, 0xdc, 0xe7, 0xad, 0x95, 0xf7, 0x51, 0x19, 0xe9, 0x93, 0x0a, 0x66, 0x7f, 0x0d,
+	0x90, 0xbf, 0x6e, 0x59, 0x1e, 0xb2, 0x0a, 0x9c, 0x5a, 0x15, 0xe4, 0x13, 0x48, 0x69, 0xae, 0x77,
+	0x09, 0x1f, 0xcd, 0x18, 0x15, 0x34, 0xa0, 0x02, 0xe7, 0xc7, 0x8b, 0x39, 0x41, 0xbe, 0x89, 0x19,
+	0xc7, 0x46, 0xdf, 0xed, 0x3c, 0xa1, 0xaf, 0x03, 0x81, 0x45, 0xf9, 0x96, 0x10, 0xef, 0x07, 0xa9,
+	0xc5, 0x7c, 0x1b, 0x35, 0xff, 0x68, 0x0f, 0xa1, 0x7f, 0x12, 0x70, 0xa1, 0x04, 0xbc, 0x95, 0x2a,
+	0x40, 0x16, 0x7b, 0xbf, 0x1b, 0x60, 0x65, 0xdc, 0x1b, 0x6b, 0x7d, 0x45, 0xdb, 0xdb, 0x75, 0x6d,
+	0x77, 0xe0, 0xe0, 0x34, 0x25, 0xa8, 0x75, 0x3f, 0x0b, 0xa5, 0xea, 0x1f, 0x27, 0x89, 0x12, 0xac,
+	0x01, 0x91, 0x43, 0x6f, 0x02, 0xe3, 0x5c, 0xf5, 0x75, 0x42, 0xbf, 0xac, 0x5d, 0xd7, 0x29, 0x5f,
+	0xb7, 0x4c, 0x3c, 0xd7, 0xfd, 0x25, 0x1c, 0x12, 0x5c, 0x53, 0x2e, 0x30, 0x69, 0xbe, 0x8b, 0x36,
+	0xb0, 0x56, 0x66, 0x60, 0xde, 0xdf, 0xf2, 0xfd, 0xd0, 0x15, 0x9e, 0xd2, 0xdd, 0x3d, 0x76, 0xfd,
+	0x02, 0xba, 0xa9, 0x80, 0xb7, 0x54, 0xb1, 0x1d, 0x15, 0x0b, 0x14, 0x3c, 0x63, 0xaf, 0x62, 0x92,
+	0xce, 0xb8, 0x65, 0x16, 0xed, 0xfd, 0x66, 0x91, 0xc9, 0x7e, 0xe7, 0xed, 0xb2, 0xdf, 0xad, 0xc9,
+	0xbe, 0x76, 0xdb, 0x5e, 0xee, 0xb6, 0x6f, 0x35, 0x82, 0x5f, 0x0d, 0x30, 0xb5, 0xf4, 0x35, 0xbe,
+	0xf2, 0x87, 0xd0, 0x25, 0x71, 0x2c, 0xb8, 0x96, 0xbd, 0x34, 0x28, 0x12, 0xd1, 0xfe, 0xb7, 0x44,
+	0x78, 0xbf, 0x19, 0x70, 0x58, 0x28, 0x57, 0x63, 0x1e, 0xd2, 0x3e, 0xf5, 0x14, 0xad, 0x5a, 0x79,
+	0x7c, 0x17, 0x36, 0x7f, 0x18, 0x60, 0x2d, 0xbf, 0x9f, 0xc6, 0xec, 0x3e, 0x5c, 0x5c, 0x18, 0x2e,
+	0x93, 0x80, 0xf1, 0x60, 0x95, 0x6b, 0xd3, 0x88, 0x94, 0xa1, 0xbb, 0x30, 0x7a, 0x06, 0x66, 0x45,
+	0x8b, 0x1a, 0x3c, 0x99, 0x08, 0xac, 0x4c, 0x64, 0xde, 0x43, 0x69, 0x7b, 0x6f, 0x3a, 0x30, 0xc8,
+	0x41, 0xfd, 0xd2, 0xe4, 0x31, 0x5d, 0xd5, 0x2a, 0xba, 0x30, 0x3c, 0x59, 0xd6, 0x1d, 0xbd, 0x0c,
+	0x55, 0x1d, 0xbf, 0x5d, 0x77, 0xfc, 0xaa, 0xa2, 0x76, 0xea, 0x8a, 0x7a, 0xdb, 0x88, 0xba, 0x7b,
+	0x8c, 0xa8, 0xdc, 0x37, 0xf4, 0x6e, 0xf5, 0x61, 0xd3, 0x89, 0xb6, 0xbf, 0x03, 0x55, 0xd3, 0x79,
+	0xbc, 0xc7, 0x20, 0xfb, 0x7b, 0x0d, 0xd2, 0x82, 0xd6, 0xd9, 0xdc, 0x19, 0xa8, 0xc7, 0xd6, 0x3a,
+	0x9b, 0x57, 0x8a, 0x13, 0x6a, 0xc5, 0x59, 0x77, 0xb2, 0xe1, 0x1e, 0x27, 0xf3, 0x61, 0xac, 0xe7,
+	0x13, 0x5c, 0x21, 0x7d, 0x8d, 0xa1, 0x33, 0x52, 0xd3, 0xea, 0xb0, 0x64, 0x58, 0xe9, 0x8a, 0xb9,
+	0x63, 0xa6, 0x0c, 0xab, 0x68, 0xa9, 0x3b, 0xb2, 0xde, 0xd9, 0x1d, 0xd5, 0x4c, 0x74, 0xfc, 0xdf,
+	0x4d, 0xb4, 0xd6, 0xb2, 0x1c, 0xde, 0x6a, 0x59, 0xd6, 0xd0, 0x55, 0xab, 0xa5, 0x3c, 0x2d, 0x77,
+	0xcf, 0x03, 0x7e, 0xa9, 0x7b, 0x76, 0x1d, 0x49, 0x69, 0x99, 0xb1, 0x10, 0x77, 0xda, 0xd6, 0xd2,
+	0x40, 0xa2, 0x17, 0xc1, 0xf5, 0x16, 0xb5, 0x62, 0xa6, 0x81, 0xcc, 0xf0, 0xf9, 0xd5, 0x62, 0x95,
+	0xd0, 0x8d, 0xd0, 0x35, 0x91, 0xc7, 0x52, 0xe6, 0xac, 0x2a, 0xcf, 0x3b, 0x1e, 0xe9, 0xc3, 0x38,
+	0x5f, 0xaa, 0xcf, 0x48, 0xcb, 0xb2, 0x0e, 0xcb, 0xb2, 0xfa, 0x8e, 0x0a, 0x86, 0x3c, 0xed, 0x3a,
+	0x47, 0x24, 0x0b, 0x5f, 0xf6, 0xd4, 0xdf, 0xb0, 0xaf, 0xfe, 0x19, 0x00, 0x85, 0x88, 0x7d, 0x4f,
+	0xa1, 0x0d, 0x00, 0x00,
 }
@@ -0,0 +1,123 @@
+// Package metrics - Prometheus collectors of DiceMix runs
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/server"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics - collectors of runs executed by client
+// registered on registry of its own, so multiple instances don't conflict
+type Metrics struct {
+	registry          *prometheus.Registry
+	sessionsStarted   prometheus.Counter
+	sessionsSucceeded prometheus.Counter
+	sessionsAborted   prometheus.Counter
+	blameStages       prometheus.Counter
+	phaseLatency      *prometheus.HistogramVec
+	anonymitySet      prometheus.Histogram
+	messages          prometheus.Histogram
+}
+
+// New creates a new Metrics instance
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		sessionsStarted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "dicemix_sessions_started_total",
+			Help: "Sessions started by server.",
+		}),
+		sessionsSucceeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "dicemix_sessions_succeeded_total",
+			Help: "Sessions in which every peer confirmed.",
+		}),
+		sessionsAborted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "dicemix_sessions_aborted_total",
+			Help: "Sessions which failed after being started.",
+		}),
+		blameStages: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "dicemix_blame_stages_total",
+			Help: "Blame stages entered.",
+		}),
+		phaseLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "dicemix_phase_latency_seconds",
+			Help:    "Time from entering phase until response arrives, by response code.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+		}, []string{"code"}),
+		anonymitySet: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "dicemix_anonymity_set_size",
+			Help:    "Peers in session including us.",
+			Buckets: prometheus.ExponentialBuckets(2, 2, 8),
+		}),
+		messages: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "dicemix_session_messages",
+			Help:    "Messages mixed in session.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 11),
+		}),
+	}
+
+	m.registry.MustRegister(m.sessionsStarted, m.sessionsSucceeded, m.sessionsAborted,
+		m.blameStages, m.phaseLatency, m.anonymitySet, m.messages)
+	return m
+}
+
+// Instrument - records runs of s
+// replaces observer and response hook of s
+func (m *Metrics) Instrument(s server.Server) {
+	s.OnEvent(m.Observe)
+	s.OnResponse(m.ObserveResponse)
+}
+
+// Observe - records event of run
+func (m *Metrics) Observe(event server.Event) {
+	switch event.Type {
+	case server.EventSessionStarted:
+		m.sessionsStarted.Inc()
+		m.anonymitySet.Observe(float64(event.Peers))
+	case server.EventRootsReceived:
+		m.messages.Observe(float64(event.Messages))
+	case server.EventSuccess:
+		m.sessionsSucceeded.Inc()
+	case server.EventBlameStarted:
+		m.blameStages.Inc()
+	case server.EventError:
+		// runs which failed before session started are'nt sessions
+		if event.SessionID != 0 {
+			m.sessionsAborted.Inc()
+		}
+	}
+}
+
+// ObserveResponse - records latency of response with code
+func (m *Metrics) ObserveResponse(code uint32, latency time.Duration) {
+	m.phaseLatency.WithLabelValues(strconv.FormatUint(uint64(code), 10)).Observe(latency.Seconds())
+}
+
+// Handler - serves metrics in Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Serve - serves metrics at /metrics on listener
+// returns once listener is closed
+func (m *Metrics) Serve(listener net.Listener) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return http.Serve(listener, mux)
+}
+
+// ListenAndServe - serves metrics at /metrics on local addr (host:port)
+func (m *Metrics) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return m.Serve(listener)
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/coordinator"
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/server"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+// serves metrics on local listener
+// returns metrics scraped from it
+func scrape(t *testing.T, m *Metrics) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go m.Serve(listener)
+
+	response, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func checkScrape(t *testing.T, body string, expected []string) {
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Error("For", "scrape", "expected", line, "got", body)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	const peers = 3
+	c := coordinator.New(coordinator.Config{Peers: peers, Timeout: 10 * time.Second})
+	m := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < peers; i++ {
+		conn := server.NewConnection("")
+		conn.SetDialer(func(ctx context.Context) (transport.Transport, error) {
+			return c.Pipe(), nil
+		})
+		m.Instrument(conn)
+
+		state := &utils.State{
+			Pool:           utils.Pool{Denomination: 100000, MinPeers: peers, SlotSize: dc.DefaultSlotSize},
+			MyMsgCount:     2,
+			MyMessages:     make([]string, 2),
+			MyMessagesHash: make([]uint64, 2),
+		}
+		state.Session.Ltpk, state.Session.Ltsk, _ = ecdsa.NewCurveECDSA().GenerateKeyPair()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := conn.Register(state); err != nil {
+				t.Error("For", "run", "expected", nil, "got", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	checkScrape(t, scrape(t, m), []string{
+		"dicemix_sessions_started_total 3",
+		"dicemix_sessions_succeeded_total 3",
+		"dicemix_sessions_aborted_total 0",
+		"dicemix_blame_stages_total 0",
+		"dicemix_anonymity_set_size_sum 9",
+		"dicemix_session_messages_sum 18",
+		`dicemix_phase_latency_seconds_count{code="101"} 3`,
+		`dicemix_phase_latency_seconds_count{code="106"} 3`,
+	})
+}
+
+func TestObserve(t *testing.T) {
+	m := New()
+
+	m.Observe(server.Event{Type: server.EventSessionStarted, SessionID: 1, Peers: 4})
+	m.Observe(server.Event{Type: server.EventBlameStarted, SessionID: 1})
+	m.Observe(server.Event{Type: server.EventError, SessionID: 1, Err: errors.New("run aborted")})
+	// run failed before session was started
+	m.Observe(server.Event{Type: server.EventError, Err: errors.New("connection refused")})
+
+	checkScrape(t, scrape(t, m), []string{
+		"dicemix_sessions_started_total 1",
+		"dicemix_sessions_succeeded_total 0",
+		"dicemix_sessions_aborted_total 1",
+		"dicemix_blame_stages_total 1",
+		`dicemix_anonymity_set_size_bucket{le="4"} 1`,
+	})
+}
@@ -6,6 +6,6 @@ import (
 
 // NIKE - The main interface for Non-interactive Key Exchange (NIKE).
 type NIKE interface {
-	GenerateKeys(*utils.State, int)
-	DeriveSharedKeys(*utils.State)
+	GenerateKeys(*utils.State, int) error
+	DeriveSharedKeys(*utils.State) error
 }
@@ -6,8 +6,6 @@ import (
 	"github.com/dev-appmonsters/dicemix-light-client/ecdh"
 	"github.com/dev-appmonsters/dicemix-light-client/rng"
 	"github.com/dev-appmonsters/dicemix-light-client/utils"
-
-	log "github.com/sirupsen/logrus"
 )
 
 type nike struct {
@@ -23,7 +21,7 @@ func NewNike() NIKE {
 // GenerateKeys -- generates random NIKE keypair
 // mode = 0 to generate (my_kesk, my_kepk)
 // mode = 1 to generate (my_next_kesk, my_next_kepk)
-func (n *nike) GenerateKeys(state *utils.State, mode int) {
+func (n *nike) GenerateKeys(state *utils.State, mode int) error {
 	// generate random key pair
 	ecdh := ecdh.NewCurve25519ECDH()
 	var err error
@@ -34,27 +32,33 @@ func (n *nike) GenerateKeys(state *utils.State, mode int) {
 	}
 
 	if err != nil {
-		log.Fatalf("Error: generating NIKE key pair %v", err)
+		return utils.NewError(utils.ErrCrypto, "generating NIKE key pair: %w", err)
 	}
+	return nil
 }
 
 // DeriveSharedKeys - derives shared keys for all peers
 // generates RNG based on shared key using ChaCha20
-func (n *nike) DeriveSharedKeys(state *utils.State) {
+func (n *nike) DeriveSharedKeys(state *utils.State) error {
 	ecdh := ecdh.NewCurve25519ECDH()
 	peersCount := len(state.Peers)
 	for i := 0; i < peersCount; i++ {
 		var pubkey, res = ecdh.Unmarshal(state.Peers[i].PubKey)
 		if !res {
-			log.Fatalf("Error: generating NIKE Shared Keys %v", res)
+			return utils.NewPeerError([]int32{state.Peers[i].ID}, "invalid NIKE public key")
 		}
 		var err error
 		state.Peers[i].SharedKey, err = ecdh.GenerateSharedSecret(state.Session.Kesk, pubkey)
 
 		if err != nil {
-			log.Fatalf("Error: generating NIKE Shared Keys %v", err)
+			return utils.NewError(utils.ErrCrypto, "generating NIKE Shared Keys: %w", err)
 		}
 
-		state.Peers[i].Dicemix = rng.NewRng(state.Peers[i].SharedKey)
+		state.Peers[i].Dicemix, err = rng.NewRng(state.Peers[i].SharedKey)
+
+		if err != nil {
+			return utils.NewError(utils.ErrCrypto, "generating RNG: %w", err)
+		}
 	}
+	return nil
 }
@@ -6,7 +6,6 @@ import (
 	"encoding/hex"
 
 	"github.com/codahale/chacha20"
-	log "github.com/sirupsen/logrus"
 )
 
 // DiceMixRng -- data structure to hold stream
@@ -18,26 +17,27 @@ type DiceMixRng struct {
 }
 
 // NewRng -- creates DiceMixRng object using seed provided
-func NewRng(seed []byte) DiceMixRng {
+// returns error if seed is not of valid size
+func NewRng(seed []byte) (DiceMixRng, error) {
 	// default -- using nonce value as 0
 	nonceHex := "0000000000000000"
 	nonce, err := hex.DecodeString(nonceHex)
 
 	if err != nil {
-		log.Fatal("Error Occured: ", err)
+		return DiceMixRng{}, err
 	}
 
 	var dicemix = DiceMixRng{}
 	dicemix.chachaStream, dicemix.chachaStreamErr = chacha20.New(seed, nonce)
 
 	if dicemix.chachaStreamErr != nil {
-		log.Fatal("Error Occured: ", dicemix.chachaStreamErr)
+		return DiceMixRng{}, dicemix.chachaStreamErr
 	}
 
 	// generate random number for DC Exponential by extracting first 8 bytes
 	dicemix.chachaExpRng = getPRG(dicemix.chachaStream, 8)
 
-	return dicemix
+	return dicemix, nil
 }
 
 // GetFieldElement - converts []byte of len 8 to uint64
@@ -45,15 +45,20 @@ func (d *DiceMixRng) GetFieldElement() uint64 {
 	return uint64(binary.LittleEndian.Uint64(d.chachaExpRng))
 }
 
-// GetBytes - returns 20 byte[]
-func (d *DiceMixRng) GetBytes(bytes uint8) []byte {
+// GetBytes - returns |bytes| byte[] to pad slot of DC-SIMPLE
+func (d *DiceMixRng) GetBytes(bytes int) []byte {
 	return getPRG(d.chachaStream, bytes)
 }
 
 // generates Rng in form of bytes[] and string from provided stream
-func getPRG(stream cipher.Stream, pos uint8) []byte {
-	src := make([]byte, 64)
-	dst := make([]byte, 64)
+// stream is consumed in blocks of 64 bytes
+func getPRG(stream cipher.Stream, pos int) []byte {
+	size := 64
+	if pos > size {
+		size = (pos + 63) / 64 * 64
+	}
+	src := make([]byte, size)
+	dst := make([]byte, size)
 
 	// stores stream bytes into dst[]
 	stream.XORKeyStream(dst, src)
@@ -3,5 +3,5 @@ package rng
 // RNG - The main interface chacha20 DiceMixRng.
 type RNG interface {
 	GetFieldElement(dicemix DiceMixRng) uint64
-	GetBytes(dicemix DiceMixRng, bytes uint8) []byte
+	GetBytes(dicemix DiceMixRng, bytes int) []byte
 }
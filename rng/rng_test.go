@@ -22,13 +22,38 @@ func decodeString(key string) []byte {
 
 func TestRng(t *testing.T) {
 	for _, pair := range testcases {
-		v := NewRng(decodeString(pair.seed))
-		if hex.EncodeToString(v.chachaExpRng) != pair.prg {
+		v, err := NewRng(decodeString(pair.seed))
+		if err != nil || hex.EncodeToString(v.chachaExpRng) != pair.prg {
 			t.Error(
 				"For", pair.seed,
 				"expected", pair.prg[0],
-				"got", v,
+				"got", v, err,
 			)
 		}
 	}
 }
+
+func TestRngInvalidSeed(t *testing.T) {
+	if _, err := NewRng(decodeString("0000")); err == nil {
+		t.Error(
+			"For", "0000",
+			"expected", "error",
+			"got", err,
+		)
+	}
+}
+
+func TestGetBytes(t *testing.T) {
+	seed := decodeString(testcases[0].seed)
+	short, _ := NewRng(seed)
+	long, _ := NewRng(seed)
+
+	// slots of up to 64 bytes consume single block of stream
+	// larger slots consume consecutive blocks
+	blocks := append(short.GetBytes(64), short.GetBytes(24)...)
+	padding := long.GetBytes(100)
+
+	if len(padding) != 100 || hex.EncodeToString(padding[:88]) != hex.EncodeToString(blocks) {
+		t.Error("For", 100, "expected", blocks, "got", padding)
+	}
+}
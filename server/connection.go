@@ -1,83 +1,262 @@
 package server
 
 import (
-	"flag"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/url"
 	"time"
 
+	"github.com/dev-appmonsters/dicemix-light-client/coinjoin"
 	"github.com/dev-appmonsters/dicemix-light-client/dc"
 	"github.com/dev-appmonsters/dicemix-light-client/messages"
 	"github.com/dev-appmonsters/dicemix-light-client/nike"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
 	"github.com/dev-appmonsters/dicemix-light-client/utils"
 
 	"github.com/golang/protobuf/proto"
-	"github.com/gorilla/websocket"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// server configurations
-var addr = flag.String("addr", "localhost:8082", "http service address")
-var dialer = websocket.Dialer{} // use default options
-
-// Exposed interfaces
-var iNike nike.NIKE
-var iDcNet dc.DC
-
+// connection with a DiceMix server
+// every connection holds its own configuration and
+// NIKE, DC instances so runs can be executed in parallel
 type connection struct {
 	Server
+	addr      string
+	nike      nike.NIKE
+	dcNet     dc.DC
+	hook      func(from, to Phase)
+	observer  func(Event)
+	responded func(code uint32, latency time.Duration)
+	timeouts  Timeouts
+	reconnect Reconnect
+	tls       *tls.Config
+	dial      transport.Dialer
+	fresh     func(run uint32) ([][]byte, error)
+	utxos     coinjoin.UTXOSource
+	logger    log.FieldLogger
 }
 
-// NewConnection creates a new Server instance
-func NewConnection() Server {
-	initialize()
+// transport with server
+// remembers last request sent to resend it on resume
+type link struct {
+	transport.Transport
+	last []byte
+}
 
-	return &connection{}
+// NewConnection creates a new Server instance
+// for server listening on addr (host:port)
+// runs with separate states can be registered concurrently
+func NewConnection(addr string) Server {
+	return &connection{
+		addr:      addr,
+		nike:      nike.NewNike(),
+		dcNet:     dc.NewDCNetwork(),
+		timeouts:  DefaultTimeouts(),
+		reconnect: DefaultReconnect(),
+		logger:    log.StandardLogger(),
+	}
 }
 
 // Register - requests to C_JOIN_REQUEST
-func (c *connection) Register(state *utils.State) {
-	var connection = connect()
-	listener(connection, state)
+// returns once transaction is successful or run fails
+func (c *connection) Register(state *utils.State) error {
+	return c.RegisterContext(context.Background(), state)
+}
+
+// RegisterContext - same as Register, run can be cancelled via ctx
+// on cancellation connection is closed and ctx.Err() is returned
+func (c *connection) RegisterContext(ctx context.Context, state *utils.State) error {
+	err := c.register(ctx, state)
+	if err != nil {
+		c.notify(state, Event{Type: EventError, Err: err})
+	}
+	return err
+}
+
+// connects to server and runs listener until run ends
+// reconnects if connection drops
+func (c *connection) register(ctx context.Context, state *utils.State) error {
+	conn, err := c.connect(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	machine := newPhaseMachine(c.hook, c.timeouts)
+	link := &link{Transport: conn}
+
+	for {
+		err = c.listen(ctx, link, state, machine)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !errors.Is(err, errConnectionLost) || c.reconnect.Attempts == 0 {
+			return err
+		}
 
-	defer connection.Close()
+		// resume session over new connection
+		// or rejoin fresh session if that is not possible
+		c.logger.Info("Connection lost - ", err)
+		if err = c.reconnectLink(ctx, link, state, machine); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+	}
 }
 
-// performs some basic initializations
-func initialize() {
-	// initailze exposed interfaes for further use
-	iNike = nike.NewNike()
-	iDcNet = dc.NewDCNetwork()
+// runs listener over link until run ends or connection drops
+// closes connection once ctx is done
+// which unblocks pending read of listener
+func (c *connection) listen(ctx context.Context, link *link, state *utils.State, machine *phaseMachine) error {
+	conn := link.Transport
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("Run cancelled - ", ctx.Err())
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	return c.listener(link, state, machine)
+}
+
+// SetTimeouts - configures read deadlines of run
+func (c *connection) SetTimeouts(timeouts Timeouts) {
+	c.timeouts = timeouts
+}
+
+// OnResponse - registers hook invoked on every response expected in current phase
+// latency is time since phase was entered
+func (c *connection) OnResponse(hook func(code uint32, latency time.Duration)) {
+	c.responded = hook
+}
+
+// SetLogger - logs progress of run via logger instead of standard logger
+func (c *connection) SetLogger(logger log.FieldLogger) {
+	c.logger = logger
+	c.dcNet = dc.NewDCNetworkWithLogger(logger)
+}
+
+// OnTransition - registers hook invoked on every phase transition
+func (c *connection) OnTransition(hook func(from, to Phase)) {
+	c.hook = hook
+}
+
+// SetDialer - replaces websocket transport with one established by dial
+// dial is invoked on every (re)connection
+func (c *connection) SetDialer(dial transport.Dialer) {
+	c.dial = dial
+}
+
+// SetFreshMessages - provides messages replacing ours once KESK revealed
+// in blame after DC-SIMPLE unmasked them, packed in slots of pool slot size
+// without provider session fails instead of mixing them again
+func (c *connection) SetFreshMessages(provider func(run uint32) ([][]byte, error)) {
+	c.fresh = provider
+}
+
+// SetUTXOs - checks inputs announced by peers against source before signing ours
+// without it amounts announced by peers are trusted
+func (c *connection) SetUTXOs(source coinjoin.UTXOSource) {
+	c.utxos = source
 }
 
 // connects to server and extablishes a web socket connection
-func connect() *websocket.Conn {
-	url := url.URL{Scheme: "ws", Host: *addr, Path: "/ws"}
-	log.Info("Connecting to ", url.String())
-	conn, _, err := dialer.Dial(url.String(), nil)
-	checkError(err)
-	log.Info("Connected to ", url.String())
+// unless other transport is configured via SetDialer
+// connection is secured via TLS if configured
+func (c *connection) connect(ctx context.Context) (transport.Transport, error) {
+	if c.dial != nil {
+		return c.dial(ctx)
+	}
 
-	// Read the message from server with deadline of ResponseWait(30) seconds
-	conn.SetReadDeadline(time.Now().Add(utils.ResponseWait * time.Second))
+	url := url.URL{Scheme: "ws", Host: c.addr, Path: "/ws"}
+	if c.tls != nil {
+		url.Scheme = "wss"
+	}
+
+	c.logger.Info("Connecting to ", url.String())
+	conn, err := transport.DialWebsocket(ctx, url.String(), c.tls)
+	if err != nil {
+		// pinning mismatch may be wrapped by handshake
+		var e *utils.Error
+		if errors.As(err, &e) && errors.Is(e, utils.ErrPinMismatch) {
+			return nil, e
+		}
+		return nil, err
+	}
+	c.logger.Info("Connected to ", url.String())
 
-	return conn
+	return conn, nil
 }
 
 // listens for responses from server side
-func listener(c *websocket.Conn, state *utils.State) {
+// returns nil once transaction is successful
+func (c *connection) listener(conn *link, state *utils.State, machine *phaseMachine) error {
+	// join pool unless session is being resumed
+	if machine.phase == PhaseJoin {
+		if err := c.sendJoinRequest(conn, state); err != nil {
+			return err
+		}
+	}
+
 	for {
-		_, message, err := c.ReadMessage()
+		// deadline is refreshed on every phase transition
+		message, err := transport.ReceiveDeadline(conn, machine.deadline())
+		if errors.Is(err, transport.ErrDeadline) {
+			return machine.timeout(err)
+		}
 		if err != nil {
-			log.Fatalf("Connection closed - %v", err)
+			return fmt.Errorf("%w: %v", errConnectionLost, err)
 		}
 
 		response := &messages.GenericResponse{}
-		err = proto.Unmarshal(message, response)
-		checkError(err)
+		if err = proto.Unmarshal(message, response); err != nil || response.Header == nil {
+			return utils.NewError(utils.ErrProtocolViolation, "malformed response: %v", err)
+		}
+
+		// rejects responses not expected in current phase
+		next, err := machine.next(response.Header, state)
+		if err != nil {
+			return withCode(err, response.Header.Code)
+		}
+
+		if c.responded != nil {
+			c.responded(response.Header.Code, time.Since(machine.entered))
+		}
 
 		// handles response and take further actions
 		// based on response.Code
-		handleMessage(c, message, response.Header.Code, state)
+		// response is handled even if our request could'nt be sent
+		// as it would be resent on resume
+		err = c.handleMessage(conn, message, response.Header.Code, state)
+		if err != nil && !errors.Is(err, errConnectionLost) {
+			return err
+		}
+
+		machine.last = response.Header.Code
+		machine.transition(next)
+
+		if err != nil {
+			return err
+		}
+
+		// blame is followed by next run among remaining peers
+		// so session ends only with successful transaction
+		if next == PhaseDone {
+			return nil
+		}
 	}
 }
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/gorilla/websocket"
+)
+
+// starts websocket server handling every connection via handler
+// returns client connection pointing to it
+func newTestServer(handler func(conn *websocket.Conn)) (*httptest.Server, *connection) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		handler(conn)
+	}))
+
+	return srv, NewConnection(strings.TrimPrefix(srv.URL, "http://")).(*connection)
+}
+
+func TestRegisterCancel(t *testing.T) {
+	// server accepts connection but never responds
+	closed := make(chan struct{})
+	srv, c := newTestServer(func(conn *websocket.Conn) {
+		// blocks until client closes connection
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				break
+			}
+		}
+		close(closed)
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	state := &utils.State{}
+	if err := c.RegisterContext(ctx, state); err != context.DeadlineExceeded {
+		t.Error("For", "cancelled run", "expected", context.DeadlineExceeded, "got", err)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("For", "cancelled run", "expected", "connection closed", "got", "connection open")
+	}
+}
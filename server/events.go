@@ -0,0 +1,90 @@
+package server
+
+import (
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+// EventType - kind of progress made by run
+type EventType uint32
+
+// events emitted during run
+const (
+	// EventJoined - server placed us in pool
+	EventJoined EventType = iota
+	// EventSessionStarted - pool is full and session is assigned
+	EventSessionStarted
+	// EventKeyExchangeDone - shared keys derived with every peer
+	EventKeyExchangeDone
+	// EventRootsReceived - message hashes solved from DC-EXP
+	EventRootsReceived
+	// EventDCSimpleSent - our DC-SIMPLE vector sent
+	EventDCSimpleSent
+	// EventConfirmationSent - our confirmation sent
+	EventConfirmationSent
+	// EventSuccess - every peer confirmed
+	EventSuccess
+	// EventBlameStarted - server requested our KESK
+	EventBlameStarted
+	// EventPeerExcluded - peer identified as disruptive in blame stage
+	EventPeerExcluded
+	// EventError - run failed
+	EventError
+	// EventRunStarted - session continues with next run excluding disruptive peers
+	EventRunStarted
+)
+
+var eventNames = map[EventType]string{
+	EventJoined:           "joined",
+	EventSessionStarted:   "session started",
+	EventKeyExchangeDone:  "key exchange done",
+	EventRootsReceived:    "roots received",
+	EventDCSimpleSent:     "dc-simple sent",
+	EventConfirmationSent: "confirmation sent",
+	EventSuccess:          "success",
+	EventBlameStarted:     "blame started",
+	EventPeerExcluded:     "peer excluded",
+	EventError:            "error",
+	EventRunStarted:       "run started",
+}
+
+func (e EventType) String() string {
+	if name, ok := eventNames[e]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Event - progress of run reported to observer
+type Event struct {
+	Type EventType
+	Time time.Time
+	// SessionID - 0 until session is started
+	SessionID uint64
+	// Peers - size of anonymity set including us, for EventSessionStarted and EventRunStarted
+	Peers int
+	// Messages - number of messages in session, for EventRootsReceived
+	Messages int
+	// Peer - Id of peer excluded, for EventPeerExcluded
+	Peer int32
+	// Err - reason run failed, for EventError
+	Err error
+}
+
+// OnEvent - registers observer invoked on every event of run
+// observer is invoked synchronously, so it should'nt block
+func (c *connection) OnEvent(observer func(Event)) {
+	c.observer = observer
+}
+
+// reports event of run in state to observer
+func (c *connection) notify(state *utils.State, event Event) {
+	if c.observer == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	event.SessionID = state.Session.SessionID
+	c.observer(event)
+}
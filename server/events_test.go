@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestEvents(t *testing.T) {
+	const sessionID = 7
+
+	dcExpLength := make(chan int, 1)
+	srv, c := newTestServer(func(conn *websocket.Conn) {
+		scriptedRun(conn, sessionID, dcExpLength)
+	})
+	defer srv.Close()
+
+	var events []Event
+	c.OnEvent(func(event Event) {
+		events = append(events, event)
+	})
+
+	state := &utils.State{
+		Pool:           utils.Pool{Denomination: 100000, MinPeers: 3, SlotSize: dc.DefaultSlotSize},
+		MyMsgCount:     1,
+		MyMessages:     make([]string, 1),
+		MyMessagesHash: make([]uint64, 1),
+	}
+	state.Session.Ltpk, state.Session.Ltsk, _ = ecdsa.NewCurveECDSA().GenerateKeyPair()
+
+	err := c.RegisterContext(context.Background(), state)
+
+	// server aborts run in DC-EXP
+	expected := []Event{
+		{Type: EventJoined},
+		{Type: EventSessionStarted, SessionID: sessionID, Peers: 3},
+		{Type: EventKeyExchangeDone, SessionID: sessionID},
+		{Type: EventError, SessionID: sessionID, Err: err},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatal("For", "aborted run", "expected", expected, "got", events)
+	}
+	for i, event := range events {
+		if event.Type != expected[i].Type || event.SessionID != expected[i].SessionID ||
+			event.Peers != expected[i].Peers || !errors.Is(event.Err, expected[i].Err) || event.Time.IsZero() {
+			t.Error("For", expected[i].Type, "expected", expected[i], "got", event)
+		}
+	}
+}
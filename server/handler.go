@@ -1,83 +1,140 @@
 package server
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/dev-appmonsters/dicemix-light-client/coinjoin"
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
 	"github.com/dev-appmonsters/dicemix-light-client/ecdh"
+	"github.com/dev-appmonsters/dicemix-light-client/logging"
 	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/solver"
 	"github.com/dev-appmonsters/dicemix-light-client/utils"
 
 	"github.com/golang/protobuf/proto"
-	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
 )
 
+// codes of commitments relayed in responses
+var commitCodes = map[uint32]uint32{
+	messages.S_EXP_DC_COMMIT:    messages.C_EXP_DC_COMMIT,
+	messages.S_SIMPLE_DC_COMMIT: messages.C_SIMPLE_DC_COMMIT,
+}
+
 // identifies response message from server
 // and passes response to appropriate handle for further operations
-func handleMessage(conn *websocket.Conn, message []byte, code uint32, state *utils.State) {
-	log.WithFields(log.Fields{
+func (c *connection) handleMessage(conn *link, message []byte, code uint32, state *utils.State) error {
+	c.logger.WithFields(log.Fields{
 		"code": code,
 	}).Info("RECV:")
 
+	var err error
 	switch code {
 	case messages.S_JOIN_RESPONSE:
 		// Response against request to join dicemix transaction
 		response := &messages.RegisterResponse{}
-		err := proto.Unmarshal(message, response)
-		checkError(err)
-		handleJoinResponse(conn, response, state)
+		if err = unmarshal(message, response); err == nil {
+			err = c.handleJoinResponse(conn, response, state)
+		}
 	case messages.S_START_DICEMIX:
 		// Response to start DiceMix Run
 		response := &messages.DiceMixResponse{}
-		err := proto.Unmarshal(message, response)
-		checkError(err)
-		handleStartDicemix(conn, response, state)
+		if err = unmarshal(message, response); err == nil {
+			err = c.handleStartDicemix(conn, response, state)
+		}
 	case messages.S_KEY_EXCHANGE:
 		// Response against request for KeyExchange
 		response := &messages.DiceMixResponse{}
-		err := proto.Unmarshal(message, response)
-		checkError(err)
-		handleKeyExchangeResponse(conn, response, state)
+		if err = unmarshal(message, response); err == nil {
+			err = c.handleKeyExchangeResponse(conn, response, state)
+		}
 	case messages.S_EXP_DC_VECTOR:
 		// contains roots of DC-Combined
 		response := &messages.DCExpResponse{}
-		err := proto.Unmarshal(message, response)
-		checkError(err)
-		handleDCExpResponse(conn, response, state)
+		if err = unmarshal(message, response); err == nil {
+			err = c.handleDCExpResponse(conn, response, state)
+		}
+	case messages.S_EXP_DC_COMMIT, messages.S_SIMPLE_DC_COMMIT:
+		// contains commitments of peers to DC vectors they reveal next
+		response := &messages.DiceMixResponse{}
+		if err = unmarshal(message, response); err == nil {
+			err = c.handleCommitResponse(conn, response, state, commitCodes[code])
+		}
 	case messages.S_SIMPLE_DC_VECTOR:
 		// conatins peers DC-SIMPLE-VECTOR's
 		response := &messages.DCSimpleResponse{}
-		err := proto.Unmarshal(message, response)
-		checkError(err)
-		handleDCSimpleResponse(conn, response, state)
+		if err = unmarshal(message, response); err == nil {
+			err = c.handleDCSimpleResponse(conn, response, state)
+		}
 	case messages.S_TX_SUCCESSFUL:
 		// conatins success message for TX
 		response := &messages.TXDoneResponse{}
-		err := proto.Unmarshal(message, response)
-		checkError(err)
-		handleTXDoneResponse(conn, response, state)
+		if err = unmarshal(message, response); err == nil {
+			err = c.handleTXDoneResponse(conn, response, state)
+		}
 	case messages.S_KESK_REQUEST:
 		response := &messages.InitiaiteKESK{}
-		err := proto.Unmarshal(message, response)
-		checkError(err)
-		handleKESKRequest(conn, response, state)
+		if err = unmarshal(message, response); err == nil {
+			err = c.handleKESKRequest(conn, response, state)
+		}
+	case messages.S_BLAME:
+		// contains KESK's revealed by peers
+		response := &messages.DiceMixResponse{}
+		if err = unmarshal(message, response); err == nil {
+			err = c.handleBlameResponse(conn, response, state)
+		}
 	}
+
+	return withCode(err, code)
+}
+
+// requests to join pool of DiceMix runs
+// with parameters provided in state.Pool
+func (c *connection) sendJoinRequest(conn *link, state *utils.State) error {
+	header := requestHeader(messages.C_JOIN_REQUEST, 0, 0, 0)
+	message, err := proto.Marshal(&messages.JoinRequest{
+		Header:       header,
+		Denomination: state.Pool.Denomination,
+		NumMsgs:      state.MyMsgCount,
+		MinPeers:     state.Pool.MinPeers,
+		Version:      utils.ProtocolVersion,
+		SlotSize:     state.Pool.SlotSize,
+		Commit:       state.Pool.Commit,
+	})
+	if err != nil {
+		return err
+	}
+
+	// cannot sign message as server does'nt have our ltpk yet
+	joinRequest, err := proto.Marshal(&messages.SignedRequest{
+		RequestData: message,
+		Signature:   []byte{},
+	})
+
+	return c.send(conn, joinRequest, err, messages.C_JOIN_REQUEST)
 }
 
 // Response against request to join dicemix transaction
-func handleJoinResponse(conn *websocket.Conn, response *messages.RegisterResponse, state *utils.State) {
-	if response.Header.Err != "" {
-		log.Fatal("Error- ", response.Header.Err)
+func (c *connection) handleJoinResponse(conn *link, response *messages.RegisterResponse, state *utils.State) error {
+	if err := serverError(response.Header); err != nil {
+		return err
 	}
+
 	// stores MyId provided by user
 	state.Session.MyID = response.Id
 
-	log.Info("MY Ltsk - ", state.Session.Ltsk)
-	log.Info("MY Ltpk - ", state.Session.Ltpk)
+	c.logger.Info("MY Ltsk - ", logging.Key(state.Session.Ltsk))
+	c.logger.Info("MY Ltpk - ", logging.Key(state.Session.Ltpk))
+
+	c.logger.Info(response.Header.Message)
+	c.logger.Info("My Id - ", state.Session.MyID)
 
-	log.Info(response.Header.Message)
-	log.Info("My Id - ", state.Session.MyID)
+	c.notify(state, Event{Type: EventJoined})
 
 	// create proto to send response against S_JOIN_RESPONSE
-	header := requestHeader(messages.C_LTPK_REQUEST, state.Session.SessionID, state.Session.MyID)
+	header := requestHeader(messages.C_LTPK_REQUEST, state.Session.SessionID, state.Session.MyID, state.Run)
 	message, _ := proto.Marshal(&messages.LtpkExchangeRequest{
 		Header:    header,
 		PublicKey: state.Session.Ltpk,
@@ -91,126 +148,281 @@ func handleJoinResponse(conn *websocket.Conn, response *messages.RegisterRespons
 	})
 
 	// send our Long Term PublicKey
-	send(conn, ltpkExchangeRequest, err, messages.C_LTPK_REQUEST)
+	return c.send(conn, ltpkExchangeRequest, err, messages.C_LTPK_REQUEST)
 }
 
 // Response to start DiceMix Run
-func handleStartDicemix(conn *websocket.Conn, response *messages.DiceMixResponse, state *utils.State) {
-	if response.Header.Err != "" {
-		log.Fatal("Error - ", response.Header.Err)
+// session continues with next run among remaining peers after blame
+func (c *connection) handleStartDicemix(conn *link, response *messages.DiceMixResponse, state *utils.State) error {
+	if err := serverError(response.Header); err != nil {
+		// server could'nt continue session without disruptive peers
+		if state.Run != 0 {
+			return utils.NewPeerError(state.MaliciousPeers, "session aborted after run %d: %v", state.Run, err)
+		}
+		return err
 	}
 
-	log.Info("DiceMix protocol has been initiated")
+	// run should honour parameters we joined pool with
+	if err := verifyPool(state, response); err != nil {
+		return err
+	}
 
-	// initialize variables
-	state.Session.SessionID = response.Header.SessionId
-	state.Peers = make([]utils.Peers, len(response.Peers)-1)
-	set := make(map[int32]struct{}, len(response.Peers)-1)
-	i := 0
+	if response.Run != state.Run+1 {
+		return utils.NewError(utils.ErrProtocolViolation, "server started run %d, expected %d", response.Run, state.Run+1)
+	}
 
-	// store peers ID's
-	// check for duplicate peer id's
-	for _, peer := range response.Peers {
-		if _, ok := set[peer.Id]; ok {
-			log.Fatal("Duplicate peer ID's: ", peer.Id)
+	if state.Run == 0 {
+		c.logger.Info("DiceMix protocol has been initiated")
+		if err := startSession(state, response); err != nil {
+			return err
 		}
+	} else if err := nextRun(state, response); err != nil {
+		return err
+	}
+	state.Run = response.Run
 
-		set[peer.Id] = struct{}{}
+	c.logger.Info("Session Id - ", state.Session.SessionID)
+	c.logger.Info("Run - ", state.Run)
+	c.logger.Info("Number of peers - ", len(state.Peers))
 
-		if peer.Id != state.Session.MyID {
-			state.Peers[i].ID = peer.Id
-			i++
-		}
+	if state.Run == 1 {
+		c.notify(state, Event{Type: EventSessionStarted, Peers: len(state.Peers) + 1})
+	} else {
+		c.notify(state, Event{Type: EventRunStarted, Peers: len(state.Peers) + 1})
 	}
 
-	log.Info("Session Id - ", state.Session.SessionID)
-	log.Info("Number of peers - ", len(state.Peers))
+	// messages unmasked in blame would link us to our slots
+	// so they are'nt mixed again, in this session or another one
+	if state.MyExposed {
+		if err := freshMessages(state, c.fresh); err != nil {
+			return err
+		}
+	}
 
-	// generates NIKE KeyPair for current run
+	// in next runs (my_kesk, my_kepk) are rotated keys announced in previous run
+	// unless blame was initiated before we announced them
 	// mode = 0 to generate (my_kesk, my_kepk)
-	iNike.GenerateKeys(state, 0)
+	if state.Run == 1 || state.Session.Kesk == nil {
+		if err := c.nike.GenerateKeys(state, 0); err != nil {
+			return err
+		}
+	}
 
-	log.Info("MY KESK - ", state.Session.Kesk)
-	log.Info("MY KEPK - ", state.Session.Kepk)
+	c.logger.Info("MY KESK - ", logging.Key(state.Session.Kesk))
+	c.logger.Info("MY KEPK - ", logging.Key(state.Session.Kepk))
 
 	// KeyExchange
 	// send our NIKE PublicKey to server
-	header := requestHeader(messages.C_KEY_EXCHANGE, state.Session.SessionID, state.Session.MyID)
+	header := requestHeader(messages.C_KEY_EXCHANGE, state.Session.SessionID, state.Session.MyID, state.Run)
 
 	ecdh := ecdh.NewCurve25519ECDH()
 	message, err := proto.Marshal(&messages.KeyExchangeRequest{
 		Header:    header,
 		PublicKey: ecdh.Marshal(state.Session.Kepk),
 		NumMsgs:   state.MyMsgCount,
+		Inputs:    coinjoin.EncodeInputs(state.MyInputs),
 	})
+	if err != nil {
+		return err
+	}
 
 	// generate signed message using our ltsk
 	keyExchangeRequest, err := generateSignedRequest(state.Session.Ltsk, message)
 
 	// send our PublicKey
-	send(conn, keyExchangeRequest, err, messages.C_KEY_EXCHANGE)
+	return c.send(conn, keyExchangeRequest, err, messages.C_KEY_EXCHANGE)
 }
 
 // Response against request for KeyExchange
-func handleKeyExchangeResponse(conn *websocket.Conn, response *messages.DiceMixResponse, state *utils.State) {
-	if response.Header.Err != "" {
-		log.Fatal("Error - ", response.Header.Err)
+func (c *connection) handleKeyExchangeResponse(conn *link, response *messages.DiceMixResponse, state *utils.State) error {
+	if err := serverError(response.Header); err != nil {
+		return err
 	}
 
-	// generate random 160 bit message
+	// generate random message filling single slot
+	// unless message was provided by user
 	for i := 0; i < int(state.MyMsgCount); i++ {
-		state.MyMessages[i] = utils.GenerateMessage()
+		if state.MyMessages[i] == "" {
+			slots, err := dc.Pack(utils.GenerateMessage(dc.SlotCapacity(state.Pool.SlotSize)), state.Pool.SlotSize)
+			if err != nil {
+				return err
+			}
+			state.MyMessages[i] = utils.BytesToBase58String(slots[0])
+		}
 	}
 
-	log.Info("My Message (1) - ", utils.Base58StringToBytes(state.MyMessages[0]))
+	c.logger.Info("My Message (1) - ", logging.Message(utils.Base58StringToBytes(state.MyMessages[0])))
+
+	// verify KEPK's of peers are signed by them
+	if err := verifyPeersInfo(state, response.Peers, messages.C_KEY_EXCHANGE); err != nil {
+		return err
+	}
+
+	// KEPK's of next runs were committed to in previous run
+	if err := verifyNextKeys(state, response.Peers); err != nil {
+		return err
+	}
 
 	// copies peers info returned from server to local state.Peers
 	// store peers PublicKey and NumMsgs
-	filterPeers(state, response.Peers)
+	if err := filterPeers(state, response.Peers); err != nil {
+		return err
+	}
 
 	// derive shared keys with peers
-	iNike.DeriveSharedKeys(state)
+	if err := c.nike.DeriveSharedKeys(state); err != nil {
+		return err
+	}
+
+	c.notify(state, Event{Type: EventKeyExchangeDone})
 
 	// generate DC Exponential Vector
-	iDcNet.DeriveMyDCVector(state)
+	if err := c.dcNet.DeriveMyDCVector(state); err != nil {
+		return err
+	}
+
+	// our DC-EXP vector is revealed once every peer has committed to its own
+	if state.Pool.Commit {
+		commitment := dc.CommitDCExp(state.Session.SessionID, state.Run, state.Session.MyID, state.MyDC)
+		return c.sendCommitment(conn, state, messages.C_EXP_DC_COMMIT, commitment)
+	}
 
-	// DC EXP
-	// send our DC-EXP vector with peers
-	header := requestHeader(messages.C_EXP_DC_VECTOR, state.Session.SessionID, state.Session.MyID)
+	return c.sendDCExpVector(conn, state)
+}
+
+// DC EXP
+// send our DC-EXP vector with peers
+func (c *connection) sendDCExpVector(conn *link, state *utils.State) error {
+	header := requestHeader(messages.C_EXP_DC_VECTOR, state.Session.SessionID, state.Session.MyID, state.Run)
 	message, err := proto.Marshal(&messages.DCExpRequest{
 		Header:      header,
 		DCExpVector: state.MyDC,
 	})
+	if err != nil {
+		return err
+	}
 
 	// generate signed message using our ltsk
 	dcExpRequest, err := generateSignedRequest(state.Session.Ltsk, message)
 
 	// send our my_dc[]
-	send(conn, dcExpRequest, err, messages.C_EXP_DC_VECTOR)
+	return c.send(conn, dcExpRequest, err, messages.C_EXP_DC_VECTOR)
+}
+
+// sends our commitment to DC vector we reveal next
+// code - C_EXP_DC_COMMIT or C_SIMPLE_DC_COMMIT
+func (c *connection) sendCommitment(conn *link, state *utils.State, code uint32, commitment []byte) error {
+	header := requestHeader(code, state.Session.SessionID, state.Session.MyID, state.Run)
+	message, err := proto.Marshal(&messages.CommitRequest{
+		Header:     header,
+		Commitment: commitment,
+	})
+	if err != nil {
+		return err
+	}
+
+	// generate signed message using our ltsk
+	commitRequest, err := generateSignedRequest(state.Session.Ltsk, message)
+
+	return c.send(conn, commitRequest, err, int(code))
+}
+
+// handles commitments of peers to DC vectors they reveal next
+// code - C_EXP_DC_COMMIT or C_SIMPLE_DC_COMMIT peers sent
+// reveals our vector as every peer has committed to its own
+func (c *connection) handleCommitResponse(conn *link, response *messages.DiceMixResponse, state *utils.State, code uint32) error {
+	if err := serverError(response.Header); err != nil {
+		return err
+	}
+
+	// verify commitments of peers are signed by them
+	if err := verifyPeersInfo(state, response.Peers, code); err != nil {
+		return err
+	}
+
+	if err := storeCommitments(state, response.Peers, code); err != nil {
+		return err
+	}
+
+	if code == messages.C_EXP_DC_COMMIT {
+		return c.sendDCExpVector(conn, state)
+	}
+	return c.sendDCSimpleVector(conn, state)
 }
 
 // obtains roots and runs DC_SIMPLE
-func handleDCExpResponse(conn *websocket.Conn, response *messages.DCExpResponse, state *utils.State) {
-	if response.Header.Err != "" {
-		log.Fatal("Error - ", response.Header.Err)
+func (c *connection) handleDCExpResponse(conn *link, response *messages.DCExpResponse, state *utils.State) error {
+	if err := serverError(response.Header); err != nil {
+		return err
 	}
 
-	// store roots (message hashes) calculated by server
-	state.AllMsgHashes = response.Roots
+	// verify DC-EXP vectors of peers are signed by them
+	if err := verifyPeersInfo(state, response.Peers, messages.C_EXP_DC_VECTOR); err != nil {
+		return err
+	}
 
-	log.Info("RECV: Roots - ", state.AllMsgHashes)
+	// copies peers info returned from server to local state.Peers
+	// store other peers DC-EXP vectors
+	if err := filterPeers(state, response.Peers); err != nil {
+		return err
+	}
+
+	// vectors revealed should match commitments of peers
+	// otherwise nothing more is revealed, server initiates blame
+	// in which peers revealing other vectors are excluded
+	if err := verifyCommitments(state, messages.C_EXP_DC_VECTOR); err != nil {
+		c.logger.Info("Awaiting blame - ", err)
+		return nil
+	}
+
+	// solve DC-EXP locally instead of trusting roots calculated by server
+	// no roots are obtained in case of collision or disruption
+	roots, err := c.dcNet.ResolveDCExp(state)
+	if errors.Is(err, solver.ErrNoSolution) {
+		c.logger.Info("Unable to resolve DC-EXP - ", err)
+		roots = []uint64{}
+	} else if err != nil {
+		return err
+	}
+
+	if err := verifyRoots(roots, response.Roots); err != nil {
+		return err
+	}
+
+	// store roots (message hashes) solved locally
+	// their order determines slots, regardless of order sent by server
+	state.AllMsgHashes = roots
+
+	c.logger.Info("RECV: Roots - ", logging.Hashes(state.AllMsgHashes))
+
+	c.notify(state, Event{Type: EventRootsReceived, Messages: len(state.AllMsgHashes)})
 
 	// run a SIMPLE DC NET
-	iDcNet.RunDCSimple(state)
+	if err := c.dcNet.RunDCSimple(state); err != nil {
+		return err
+	}
 
 	if state.Session.NextKepk == nil {
 		// generates NIKE KeyPair for next run
 		// mode = 1 to generate (my_next_kesk, my_next_kepk)
-		iNike.GenerateKeys(state, 1)
+		if err := c.nike.GenerateKeys(state, 1); err != nil {
+			return err
+		}
 	}
 
-	// send our DC SIMPLE Vector
-	header := requestHeader(messages.C_SIMPLE_DC_VECTOR, state.Session.SessionID, state.Session.MyID)
+	// our DC-SIMPLE vector is revealed once every peer has committed to its own
+	if state.Pool.Commit {
+		commitment := dc.CommitDCSimple(state.Session.SessionID, state.Run, state.Session.MyID, state.DCSimpleVector)
+		return c.sendCommitment(conn, state, messages.C_SIMPLE_DC_COMMIT, commitment)
+	}
+
+	return c.sendDCSimpleVector(conn, state)
+}
+
+// send our DC SIMPLE Vector
+// along with KEPK for next run
+func (c *connection) sendDCSimpleVector(conn *link, state *utils.State) error {
+	header := requestHeader(messages.C_SIMPLE_DC_VECTOR, state.Session.SessionID, state.Session.MyID, state.Run)
 
 	ecdh := ecdh.NewCurve25519ECDH()
 	message, err := proto.Marshal(&messages.DCSimpleRequest{
@@ -219,103 +431,237 @@ func handleDCExpResponse(conn *websocket.Conn, response *messages.DCExpResponse,
 		MyOk:           state.MyOk,
 		NextPublicKey:  ecdh.Marshal(state.Session.NextKepk),
 	})
+	if err != nil {
+		return err
+	}
 
 	// generate signed message using our ltsk
 	dcSimpleRequest, err := generateSignedRequest(state.Session.Ltsk, message)
 
-	send(conn, dcSimpleRequest, err, messages.C_SIMPLE_DC_VECTOR)
+	// vector is resent on resume even if it could'nt be sent now
+	state.DCSimpleSent = err == nil
+
+	if err = c.send(conn, dcSimpleRequest, err, messages.C_SIMPLE_DC_VECTOR); err != nil {
+		return err
+	}
+
+	c.notify(state, Event{Type: EventDCSimpleSent})
+	return nil
 }
 
 // handles other peers DC-SIMPLE-VECTORS
 // resolves DC-NET
-func handleDCSimpleResponse(conn *websocket.Conn, response *messages.DCSimpleResponse, state *utils.State) {
-	if response.Header.Err != "" {
-		log.Fatal("Error - ", response.Header.Err)
+func (c *connection) handleDCSimpleResponse(conn *link, response *messages.DCSimpleResponse, state *utils.State) error {
+	if err := serverError(response.Header); err != nil {
+		return err
+	}
+
+	// verify DC-SIMPLE vectors of peers are signed by them
+	if err := verifyPeersInfo(state, response.Peers, messages.C_SIMPLE_DC_VECTOR); err != nil {
+		return err
 	}
 
 	// copies peers info returned from server to local state.Peers
 	// store other peers DC Simple Vectors
-	filterPeers(state, response.Peers)
+	if err := filterPeers(state, response.Peers); err != nil {
+		return err
+	}
 
 	// finally resolves DC Net Vectors to obtain messages
 	// should contain all honest peers messages in absence of malicious peers
-	state.AllMessages = response.Messages
+	allMessages, err := c.dcNet.ResolveDCSimple(state)
+	if err != nil {
+		return err
+	}
 
-	log.Info("All Messages = ", state.AllMessages)
+	// messages resolved by server should be same as ours
+	if !equalSlots(allMessages, response.Messages) {
+		return utils.NewError(utils.ErrProtocolViolation, "messages resolved by server does'nt match with messages resolved locally")
+	}
+
+	state.AllMessages = allMessages
+
+	c.logger.Info("All Messages = ", logging.Message(state.AllMessages))
 
 	// Verify that every peer agrees to proceed
-	confirmation := iDcNet.VerifyProceed(state)
+	confirmation := c.dcNet.VerifyProceed(state)
+
+	// vectors revealed should match commitments of peers
+	// we disagree otherwise, so that peers revealing other vectors are blamed
+	if err := verifyCommitments(state, messages.C_SIMPLE_DC_VECTOR); err != nil {
+		c.logger.Info("Initiating blame - ", err)
+		confirmation = false
+	}
 
-	log.Info("Agree to Proceed? = ", confirmation)
+	// messages are outputs of coinjoin if we spend inputs
+	// we agree only if transaction can be assembled from them
+	// and confirm by signing our inputs
+	var signatures []utils.Signature
+	if confirmation && len(state.MyInputs) != 0 {
+		if signatures, confirmation, err = c.signTransaction(state); err != nil {
+			return err
+		}
+	}
+
+	c.logger.Info("Agree to Proceed? = ", confirmation)
 
 	// send our Confirmation
-	header := requestHeader(messages.C_TX_CONFIRMATION, state.Session.SessionID, state.Session.MyID)
+	header := requestHeader(messages.C_TX_CONFIRMATION, state.Session.SessionID, state.Session.MyID, state.Run)
 	message, err := proto.Marshal(&messages.ConfirmationRequest{
 		Header:       header,
 		Confirmation: confirmation,
+		Signatures:   coinjoin.EncodeSignatures(signatures),
 	})
+	if err != nil {
+		return err
+	}
 
 	// generate signed message using our ltsk
 	confirmationRequest, err := generateSignedRequest(state.Session.Ltsk, message)
 
-	send(conn, confirmationRequest, err, messages.C_TX_CONFIRMATION)
+	if err = c.send(conn, confirmationRequest, err, messages.C_TX_CONFIRMATION); err != nil {
+		return err
+	}
+
+	c.notify(state, Event{Type: EventConfirmationSent})
+	return nil
 }
 
 // handles success message for TX
-func handleTXDoneResponse(conn *websocket.Conn, response *messages.TXDoneResponse, state *utils.State) {
-	if response.Header.Err != "" {
-		log.Fatal("Error - ", response.Header.Err)
+func (c *connection) handleTXDoneResponse(conn *link, response *messages.TXDoneResponse, state *utils.State) error {
+	if err := serverError(response.Header); err != nil {
+		return err
+	}
+
+	// transaction signed by every peer should be verified before success
+	if len(state.MyInputs) != 0 {
+		if err := c.verifyTransaction(state, response); err != nil {
+			return err
+		}
+		c.logger.Info("Transaction - ", logging.Transaction(hex.EncodeToString(state.Transaction)))
 	}
 
 	// transaction is successfull
-	// close the connection
-	log.Info("Transaction successful. All peers agreed.")
+	// close the connection, run is complete even if close fails
+	c.logger.Info("Transaction successful. All peers agreed.")
+	c.notify(state, Event{Type: EventSuccess})
 	conn.Close()
+	return nil
 }
 
 // handles request from server to initiate kesk
 // sends our kesk for current round
-// initializes - (my_kesk, my_kepk) := (my_next_kesk, my_next_kepk)
-func handleKESKRequest(conn *websocket.Conn, response *messages.InitiaiteKESK, state *utils.State) {
-	if response.Header.Err != "" {
-		log.Fatal("Error - ", response.Header.Err)
+func (c *connection) handleKESKRequest(conn *link, response *messages.InitiaiteKESK, state *utils.State) error {
+	if err := serverError(response.Header); err != nil {
+		return err
 	}
 
 	// request to send our KESK to initiate blame stage
-	log.Info("RECV: ", response.Header.Message)
+	c.logger.Info("RECV: ", response.Header.Message)
+	c.notify(state, Event{Type: EventBlameStarted})
+
+	// peers can unmask our slots of DC-SIMPLE using our KESK
+	if state.DCSimpleSent {
+		state.MyExposed = true
+	}
 
 	// send our kesk
-	header := requestHeader(messages.C_KESK_RESPONSE, state.Session.SessionID, state.Session.MyID)
+	header := requestHeader(messages.C_KESK_RESPONSE, state.Session.SessionID, state.Session.MyID, state.Run)
 
 	ecdh := ecdh.NewCurve25519ECDH()
 	message, err := proto.Marshal(&messages.InitiaiteKESKResponse{
 		Header:     header,
 		PrivateKey: ecdh.MarshalSK(state.Session.Kesk),
 	})
+	if err != nil {
+		return err
+	}
 
 	// generate signed message using our ltsk
 	initiaiteKESK, err := generateSignedRequest(state.Session.Ltsk, message)
 
 	// send our kesk
-	send(conn, initiaiteKESK, err, messages.C_KESK_RESPONSE)
+	return c.send(conn, initiaiteKESK, err, messages.C_KESK_RESPONSE)
+}
+
+// handles KESK's revealed by peers
+// identifies peers which disrupted current run and excludes them
+// initializes - (my_kesk, my_kepk) := (my_next_kesk, my_next_kepk)
+func (c *connection) handleBlameResponse(conn *link, response *messages.DiceMixResponse, state *utils.State) error {
+	if err := serverError(response.Header); err != nil {
+		return err
+	}
+
+	// verify KESK's and DC vectors of peers are signed by them
+	if err := verifyPeersInfo(state, response.Peers, messages.C_KESK_RESPONSE, messages.C_EXP_DC_VECTOR); err != nil {
+		return err
+	}
+
+	// store KESK's and DC vectors of peers
+	storeRevealedKeys(state, response.Peers)
+
+	// recompute peers DC vectors to identify malicious peers
+	// peers blamed in every run of session are accumulated
+	blamed := c.dcNet.Blame(state)
+	state.MaliciousPeers = append(state.MaliciousPeers, blamed...)
+
+	c.logger.Info("Peers who disrupted the run - ", blamed)
+	for _, peer := range blamed {
+		c.notify(state, Event{Type: EventPeerExcluded, Peer: peer})
+	}
+
+	// next run is carried out among remaining peers
+	excludePeers(state, blamed)
 
 	// Rotate keys
-	state.Session.Kesk = state.Session.NextKesk
-	state.Session.Kepk = state.Session.NextKepk
+	// our kesk has been revealed, so it can't be used further
+	rotateKeys(state)
 
-	// set next round keys to nil
-	state.Session.NextKesk = nil
-	state.Session.NextKepk = nil
+	return nil
 }
 
 // checks for potential errors
 // sends message to server
-func send(conn *websocket.Conn, request []byte, err error, code int) {
-	checkError(err)
-	err = conn.WriteMessage(websocket.BinaryMessage, request)
-	checkError(err)
+func (c *connection) send(conn *link, request []byte, err error, code int) error {
+	if err != nil {
+		return err
+	}
+
+	// remember request to resend it if session is resumed
+	conn.last = request
 
-	log.WithFields(log.Fields{
+	if err = conn.Send(request); err != nil {
+		return fmt.Errorf("%w: unable to send message with code %d: %v", errConnectionLost, code, err)
+	}
+
+	c.logger.WithFields(log.Fields{
 		"code": code,
 	}).Info("SENT: ")
+
+	return nil
+}
+
+// returns error reported by server in response header
+func serverError(header *messages.ResponseHeader) error {
+	if header.Err != "" {
+		return &utils.Error{Kind: utils.ErrServer, Err: errors.New(header.Err)}
+	}
+	return nil
+}
+
+// decodes response of server
+func unmarshal(message []byte, response proto.Message) error {
+	if err := proto.Unmarshal(message, response); err != nil {
+		return utils.NewError(utils.ErrProtocolViolation, "malformed response: %w", err)
+	}
+	return nil
+}
+
+// annotates error with code of message being handled
+func withCode(err error, code uint32) error {
+	var e *utils.Error
+	if errors.As(err, &e) && e.Code == 0 {
+		e.Code = code
+	}
+	return err
 }
@@ -1,23 +1,30 @@
 package server
 
 import (
+	"bytes"
+	"fmt"
+	"sort"
 	"time"
 
+	"github.com/dev-appmonsters/dicemix-light-client/coinjoin"
 	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
 	"github.com/dev-appmonsters/dicemix-light-client/messages"
 	"github.com/dev-appmonsters/dicemix-light-client/utils"
 
 	"github.com/golang/protobuf/proto"
-	log "github.com/sirupsen/logrus"
 )
 
 // copies peers info returned from server to local state.Peers
-func filterPeers(state *utils.State, peers []*messages.PeersInfo) {
+// peers are never dropped on word of server, every one of them should be relayed
+func filterPeers(state *utils.State, peers []*messages.PeersInfo) error {
 	// insanity check
 	// if server sends more peers than actually involved in run
 	// +1 represents peer himself, as server broadcast all clients info including his
 	if len(state.Peers)+1 < len(peers) {
-		log.Fatal("Error: obtained more peers from that we started. Expected - ", len(state.Peers), ", Obtained - ", len(peers))
+		return utils.NewError(utils.ErrProtocolViolation, "obtained more peers from that we started. Expected - %d, Obtained - %d", len(state.Peers), len(peers))
+	}
+	if err := verifyRelayed(state, peers); err != nil {
+		return err
 	}
 
 	// stores current peers info
@@ -42,25 +49,259 @@ func filterPeers(state *utils.State, peers []*messages.PeersInfo) {
 		var tempPeer utils.Peers
 
 		tempPeer.ID = peer.Id
+		tempPeer.LTPubKey = peerIDs[peer.Id].LTPubKey
 		tempPeer.PubKey = peer.PublicKey
+		tempPeer.NextPubKey = peer.NextPublicKey
 		tempPeer.NumMsgs = peer.NumMsgs
 		tempPeer.SharedKey = peerIDs[peer.Id].SharedKey
 		tempPeer.Dicemix = peerIDs[peer.Id].Dicemix
+		tempPeer.DCVector = peer.DCVector
 		tempPeer.DCSimpleVector = peer.DCSimpleVector
 		tempPeer.Ok = peer.OK
 		tempPeer.Confirmation = peer.Confirmation
+		tempPeer.Inputs = coinjoin.DecodeInputs(peer.Inputs)
+		tempPeer.DCExpCommitment = peerIDs[peer.Id].DCExpCommitment
+		tempPeer.DCSimpleCommitment = peerIDs[peer.Id].DCSimpleCommitment
+
+		// server may not relay KEPK's after KeyExchange
+		if len(peer.PublicKey) == 0 {
+			tempPeer.PubKey = peerIDs[peer.Id].PubKey
+			tempPeer.NumMsgs = peerIDs[peer.Id].NumMsgs
+			tempPeer.Inputs = peerIDs[peer.Id].Inputs
+		}
+
+		// KEPK for next run is announced only in DC-SIMPLE
+		if len(peer.NextPublicKey) == 0 {
+			tempPeer.NextPubKey = peerIDs[peer.Id].NextPubKey
+		}
 
 		// add peer info to our peers
 		state.Peers = append(state.Peers, tempPeer)
 	}
+
+	return nil
+}
+
+// stores session id and peers of first run of session
+// LTPK's are used to verify peers broadcasts relayed by server
+func startSession(state *utils.State, response *messages.DiceMixResponse) error {
+	state.Session.SessionID = response.Header.SessionId
+	state.Peers = make([]utils.Peers, 0, len(response.Peers))
+	set := make(map[int32]struct{}, len(response.Peers))
+
+	// store peers ID's
+	// check for duplicate peer id's
+	for _, peer := range response.Peers {
+		if _, ok := set[peer.Id]; ok {
+			return utils.NewError(utils.ErrProtocolViolation, "duplicate peer ID's: %d", peer.Id)
+		}
+
+		set[peer.Id] = struct{}{}
+
+		if peer.Id != state.Session.MyID {
+			state.Peers = append(state.Peers, utils.Peers{ID: peer.Id, LTPubKey: peer.LTPublicKey})
+		}
+	}
+
+	return nil
+}
+
+// prepares state for next run of session
+// server should exclude exactly the peers we blamed in previous run
+// every remaining peer should use KEPK it announced for next run, if any
+func nextRun(state *utils.State, response *messages.DiceMixResponse) error {
+	remaining := make(map[int32]utils.Peers, len(state.Peers))
+	for _, peer := range state.Peers {
+		remaining[peer.ID] = peer
+	}
+
+	peers := make([]utils.Peers, 0, len(state.Peers))
+	for _, info := range response.Peers {
+		if info.Id == state.Session.MyID {
+			continue
+		}
+
+		peer, ok := remaining[info.Id]
+		if !ok {
+			return utils.NewError(utils.ErrProtocolViolation, "run %d includes peer %d which is'nt one of remaining peers", response.Run, info.Id)
+		}
+		if len(info.LTPublicKey) != 0 && !bytes.Equal(info.LTPublicKey, peer.LTPubKey) {
+			return utils.NewError(utils.ErrProtocolViolation, "LTPK of peer %d changed within session", info.Id)
+		}
+		delete(remaining, info.Id)
+
+		// KEPK announced for next run is verified in KeyExchange
+		peers = append(peers, utils.Peers{ID: peer.ID, LTPubKey: peer.LTPubKey, PubKey: peer.NextPubKey})
+	}
+
+	if len(remaining) != 0 {
+		excluded := make([]int32, 0, len(remaining))
+		for id := range remaining {
+			excluded = append(excluded, id)
+		}
+		sort.Slice(excluded, func(i, j int) bool { return excluded[i] < excluded[j] })
+		return utils.NewError(utils.ErrProtocolViolation, "run %d excludes peers %v which were'nt blamed", response.Run, excluded)
+	}
+
+	// clears info of previous run
+	state.Peers = peers
+	state.AllMsgHashes = nil
+	state.MyDC = nil
+	state.MyOk = false
+	state.DCSimpleVector = nil
+	state.DCSimpleSent = false
+	state.AllMessages = nil
+	state.Transaction = nil
+
+	return nil
+}
+
+// removes peers blamed in blame stage
+// they are'nt part of next run of session
+func excludePeers(state *utils.State, blamed []int32) {
+	set := make(map[int32]struct{}, len(blamed))
+	for _, id := range blamed {
+		set[id] = struct{}{}
+	}
+
+	peers := make([]utils.Peers, 0, len(state.Peers))
+	for _, peer := range state.Peers {
+		if _, ok := set[peer.ID]; !ok {
+			peers = append(peers, peer)
+		}
+	}
+	state.Peers = peers
+}
+
+// stores commitments of peers to DC vectors they reveal next
+// code - C_EXP_DC_COMMIT or C_SIMPLE_DC_COMMIT
+// every peer should commit before vectors are revealed
+func storeCommitments(state *utils.State, peers []*messages.PeersInfo, code uint32) error {
+	var commitments = make(map[int32][]byte)
+	for _, peer := range peers {
+		commitments[peer.Id] = peer.Commitment
+	}
+
+	missing := make([]int32, 0)
+	for i := range state.Peers {
+		commitment := commitments[state.Peers[i].ID]
+		if len(commitment) == 0 {
+			missing = append(missing, state.Peers[i].ID)
+			continue
+		}
+
+		if code == messages.C_EXP_DC_COMMIT {
+			state.Peers[i].DCExpCommitment = commitment
+		} else {
+			state.Peers[i].DCSimpleCommitment = commitment
+		}
+	}
+
+	if len(missing) != 0 {
+		return utils.NewPeerError(missing, "no commitment to DC vector")
+	}
+	return nil
+}
+
+// stores KESK's and DC vectors revealed by peers in blame stage
+// keeps rest of peers info obtained in previous stages
+func storeRevealedKeys(state *utils.State, peers []*messages.PeersInfo) {
+	// map peersInfo to their ids
+	var peersInfo = make(map[int32]*messages.PeersInfo)
+	for _, peer := range peers {
+		peersInfo[peer.Id] = peer
+	}
+
+	for i := range state.Peers {
+		peer, ok := peersInfo[state.Peers[i].ID]
+		if !ok {
+			continue
+		}
+
+		state.Peers[i].Kesk = peer.PrivateKey
+		state.Peers[i].DCVector = peer.DCVector
+
+		// DC-SIMPLE vectors are'nt available if blame
+		// was initiated before DC-SIMPLE
+		if len(peer.DCSimpleVector) != 0 {
+			state.Peers[i].DCSimpleVector = peer.DCSimpleVector
+			state.Peers[i].Ok = peer.OK
+		}
+	}
+}
+
+// clears info of current run to join fresh session
+// keeps pool, our long term keys and messages, along with whether they were exposed
+func resetRun(state *utils.State) {
+	pool, session, myMessages, myInputs, myExposed := state.Pool, state.Session, state.MyMessages, state.MyInputs, state.MyExposed
+
+	*state = utils.State{}
+	state.Pool = pool
+	state.MyInputs = myInputs
+	state.Session.Ltsk = session.Ltsk
+	state.Session.Ltpk = session.Ltpk
+	state.MyMsgCount = uint32(len(myMessages))
+	state.MyMessages = myMessages
+	state.MyMessagesHash = make([]uint64, len(myMessages))
+	state.MyExposed = myExposed
+}
+
+// replaces our messages exposed in blame with ones obtained from provider
+// every fresh message should fill single slot and differ from exposed ones
+func freshMessages(state *utils.State, provider func(run uint32) ([][]byte, error)) error {
+	if provider == nil {
+		return utils.NewPeerError(state.MaliciousPeers, "our messages were unmasked in blame, no fresh messages to mix in run %d", state.Run)
+	}
+
+	messages, err := provider(state.Run)
+	if err != nil {
+		return fmt.Errorf("unable to obtain fresh messages for run %d: %w", state.Run, err)
+	}
+	if len(messages) == 0 || len(messages) > utils.MaxAllowedMessages {
+		return fmt.Errorf("%d fresh messages, expected 1 to %d", len(messages), utils.MaxAllowedMessages)
+	}
+
+	exposed := make(map[string]struct{}, len(state.MyMessages))
+	for _, message := range state.MyMessages {
+		exposed[message] = struct{}{}
+	}
+
+	myMessages := make([]string, len(messages))
+	for i, message := range messages {
+		if len(message) != int(state.Pool.SlotSize) {
+			return fmt.Errorf("fresh message %d of %d bytes, expected slot of %d bytes", i, len(message), state.Pool.SlotSize)
+		}
+
+		myMessages[i] = utils.BytesToBase58String(message)
+		if _, ok := exposed[myMessages[i]]; ok {
+			return fmt.Errorf("fresh message %d was unmasked in blame", i)
+		}
+	}
+
+	state.MyMsgCount = uint32(len(myMessages))
+	state.MyMessages = myMessages
+	state.MyMessagesHash = make([]uint64, len(myMessages))
+	state.MyExposed = false
+	return nil
+}
+
+// initializes - (my_kesk, my_kepk) := (my_next_kesk, my_next_kepk)
+func rotateKeys(state *utils.State) {
+	state.Session.Kesk = state.Session.NextKesk
+	state.Session.Kepk = state.Session.NextKepk
+
+	// set next round keys to nil
+	state.Session.NextKesk = nil
+	state.Session.NextKepk = nil
 }
 
 // generates a RequestHeader proto
-func requestHeader(code uint32, sessionID uint64, id int32) *messages.RequestHeader {
+func requestHeader(code uint32, sessionID uint64, id int32, run uint32) *messages.RequestHeader {
 	return &messages.RequestHeader{
 		Code:      code,
 		SessionId: sessionID,
 		Id:        id,
+		Run:       run,
 		Timestamp: timestamp(),
 	}
 }
@@ -70,20 +311,17 @@ func requestHeader(code uint32, sessionID uint64, id int32) *messages.RequestHea
 func generateSignedRequest(privateKey, message []byte) ([]byte, error) {
 	ecdsa := ecdsa.NewCurveECDSA()
 
+	signature := ecdsa.Sign(privateKey, message)
+	if signature == nil {
+		return nil, utils.NewError(utils.ErrCrypto, "unable to sign message")
+	}
+
 	return proto.Marshal(&messages.SignedRequest{
 		RequestData: message,
-		Signature:   ecdsa.Sign(privateKey, message),
+		Signature:   signature,
 	})
 }
 
-// checks for any potential errors
-// exists program if one found
-func checkError(err error) {
-	if err != nil {
-		log.Fatalf("Error - %v", err)
-	}
-}
-
 // to identify time of occurence of an event
 // returns current timestamp
 // example - 2018-08-07 12:04:46.456601867 +0000 UTC m=+0.000753626
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"sort"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+// Phase - stage of DiceMix run client is in
+// every phase waits for one of its expected responses from server
+type Phase uint32
+
+// DiceMix run phases
+const (
+	// PhaseJoin - C_JOIN_REQUEST sent, waiting for S_JOIN_RESPONSE
+	PhaseJoin Phase = iota
+	// PhaseLTPK - LTPK sent, waiting for S_START_DICEMIX
+	PhaseLTPK
+	// PhaseKeyExchange - KEPK sent, waiting for KEPK's of peers
+	PhaseKeyExchange
+	// PhaseDCExpCommit - commitment to DC-EXP vector sent, waiting for commitments of peers
+	PhaseDCExpCommit
+	// PhaseDCExp - DC-EXP vector sent, waiting for roots
+	PhaseDCExp
+	// PhaseDCSimpleCommit - commitment to DC-SIMPLE vector sent, waiting for commitments of peers
+	PhaseDCSimpleCommit
+	// PhaseDCSimple - DC-SIMPLE vector sent, waiting for peers DC-SIMPLE vectors
+	PhaseDCSimple
+	// PhaseConfirmation - confirmation sent, waiting for result of run
+	PhaseConfirmation
+	// PhaseBlame - KESK revealed, waiting for KESK's of peers
+	PhaseBlame
+	// PhaseNextRun - disruptive peers excluded, waiting for next run of session
+	PhaseNextRun
+	// PhaseDone - run is over, no further responses are expected
+	PhaseDone
+)
+
+var phaseNames = map[Phase]string{
+	PhaseJoin:           "join",
+	PhaseLTPK:           "ltpk",
+	PhaseKeyExchange:    "key exchange",
+	PhaseDCExpCommit:    "dc-exp commit",
+	PhaseDCExp:          "dc-exp",
+	PhaseDCSimpleCommit: "dc-simple commit",
+	PhaseDCSimple:       "dc-simple",
+	PhaseConfirmation:   "confirmation",
+	PhaseBlame:          "blame",
+	PhaseNextRun:        "next run",
+	PhaseDone:           "done",
+}
+
+func (p Phase) String() string {
+	if name, ok := phaseNames[p]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// legal transitions of every phase
+// maps code of response expected in phase to phase it leads to
+// server can initiate blame stage once DC-EXP vectors are broadcasted
+// after blame session continues with next run among remaining peers
+var transitions = map[Phase]map[uint32]Phase{
+	PhaseJoin: {
+		messages.S_JOIN_RESPONSE: PhaseLTPK,
+	},
+	PhaseLTPK: {
+		messages.S_START_DICEMIX: PhaseKeyExchange,
+	},
+	PhaseKeyExchange: {
+		messages.S_KEY_EXCHANGE: PhaseDCExp,
+	},
+	PhaseDCExp: {
+		messages.S_EXP_DC_VECTOR: PhaseDCSimple,
+		messages.S_KESK_REQUEST:  PhaseBlame,
+	},
+	PhaseDCSimple: {
+		messages.S_SIMPLE_DC_VECTOR: PhaseConfirmation,
+		messages.S_KESK_REQUEST:     PhaseBlame,
+	},
+	PhaseConfirmation: {
+		messages.S_TX_SUCCESSFUL: PhaseDone,
+		messages.S_KESK_REQUEST:  PhaseBlame,
+	},
+	PhaseBlame: {
+		messages.S_BLAME: PhaseNextRun,
+	},
+	PhaseNextRun: {
+		messages.S_START_DICEMIX: PhaseKeyExchange,
+	},
+}
+
+// transitions replaced in runs committing to DC vectors
+// every DC vector is revealed only after commitments of all peers are received
+var commitTransitions = map[Phase]map[uint32]Phase{
+	PhaseKeyExchange: {
+		messages.S_KEY_EXCHANGE: PhaseDCExpCommit,
+	},
+	PhaseDCExpCommit: {
+		messages.S_EXP_DC_COMMIT: PhaseDCExp,
+	},
+	PhaseDCExp: {
+		messages.S_EXP_DC_VECTOR: PhaseDCSimpleCommit,
+		messages.S_KESK_REQUEST:  PhaseBlame,
+	},
+	PhaseDCSimpleCommit: {
+		messages.S_SIMPLE_DC_COMMIT: PhaseDCSimple,
+		messages.S_KESK_REQUEST:     PhaseBlame,
+	},
+}
+
+// returns legal transitions of phase
+// in runs with or without commitments
+func phaseTransitions(p Phase, commit bool) map[uint32]Phase {
+	if next, ok := commitTransitions[p]; ok && commit {
+		return next
+	}
+	return transitions[p]
+}
+
+// Expects - returns codes of responses expected in phase
+// of run without commitments
+func (p Phase) Expects() []uint32 {
+	return p.expects(false)
+}
+
+func (p Phase) expects(commit bool) []uint32 {
+	next := phaseTransitions(p, commit)
+	codes := make([]uint32, 0, len(next))
+	for code := range next {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// tracks phase of current run and its deadlines
+// hook is invoked on every transition
+type phaseMachine struct {
+	phase    Phase
+	hook     func(from, to Phase)
+	timeouts Timeouts
+	started  time.Time
+	// time current phase was entered
+	entered time.Time
+	// peers which server reported as not yet delivered their message
+	// in current phase, cleared on every transition
+	pending []int32
+	// code of last response handled
+	last uint32
+}
+
+// creates phaseMachine starting from PhaseJoin
+// session budget starts from now
+func newPhaseMachine(hook func(from, to Phase), timeouts Timeouts) *phaseMachine {
+	return &phaseMachine{
+		phase:    PhaseJoin,
+		hook:     hook,
+		timeouts: timeouts,
+		started:  time.Now(),
+		entered:  time.Now(),
+	}
+}
+
+// checks if response with header can be handled in current phase
+// returns phase to move to once response is handled
+func (m *phaseMachine) next(header *messages.ResponseHeader, state *utils.State) (Phase, error) {
+	next, ok := phaseTransitions(m.phase, state.Pool.Commit)[header.Code]
+	if !ok {
+		return m.phase, utils.NewError(utils.ErrProtocolViolation,
+			"unexpected response in %s phase, expected one of %v", m.phase, m.phase.expects(state.Pool.Commit))
+	}
+
+	// session is established by S_START_DICEMIX
+	// every further response should belong to it
+	if m.phase > PhaseLTPK && header.SessionId != state.Session.SessionID {
+		return m.phase, utils.NewError(utils.ErrProtocolViolation,
+			"response for session %d, active session %d", header.SessionId, state.Session.SessionID)
+	}
+
+	return next, nil
+}
+
+// moves to phase provided
+func (m *phaseMachine) transition(to Phase) {
+	from := m.phase
+	m.phase = to
+	m.entered = time.Now()
+	m.pending = nil
+
+	if m.hook != nil {
+		m.hook(from, to)
+	}
+}
+
+// returns read deadline of current phase
+// bounded by overall session budget
+func (m *phaseMachine) deadline() time.Time {
+	deadline := time.Now().Add(m.timeouts.phase(m.phase))
+	if m.timeouts.Session != 0 && m.sessionDeadline().Before(deadline) {
+		return m.sessionDeadline()
+	}
+	return deadline
+}
+
+func (m *phaseMachine) sessionDeadline() time.Time {
+	return m.started.Add(m.timeouts.Session)
+}
+
+// stores peers which have not delivered their message in current phase
+// according to status reported by server
+// responses ending a phase are'nt its status, as they describe previous phase
+func (m *phaseMachine) received(peers []*messages.PeersInfo) {
+	m.pending = make([]int32, 0)
+	for _, peer := range peers {
+		if !peer.MessageReceived {
+			m.pending = append(m.pending, peer.Id)
+		}
+	}
+}
+
+// returns error naming phase which timed out
+// and peers which server reported as pending
+func (m *phaseMachine) timeout(cause error) error {
+	err := utils.NewError(utils.ErrTimeout, "no response in %s phase: %w", m.phase, cause)
+	if m.timeouts.Session != 0 && !time.Now().Before(m.sessionDeadline()) {
+		err = utils.NewError(utils.ErrTimeout, "session budget of %v exceeded in %s phase: %w", m.timeouts.Session, m.phase, cause)
+	}
+
+	err.Peers = m.pending
+	return err
+}
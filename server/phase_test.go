@@ -0,0 +1,218 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+type phaseTest struct {
+	codes    []uint32
+	expected []Phase
+}
+
+var phaseTests = []phaseTest{
+	{
+		// successful run
+		[]uint32{messages.S_JOIN_RESPONSE, messages.S_START_DICEMIX, messages.S_KEY_EXCHANGE,
+			messages.S_EXP_DC_VECTOR, messages.S_SIMPLE_DC_VECTOR, messages.S_TX_SUCCESSFUL},
+		[]Phase{PhaseLTPK, PhaseKeyExchange, PhaseDCExp, PhaseDCSimple, PhaseConfirmation, PhaseDone},
+	},
+	{
+		// blame after confirmation
+		[]uint32{messages.S_JOIN_RESPONSE, messages.S_START_DICEMIX, messages.S_KEY_EXCHANGE,
+			messages.S_EXP_DC_VECTOR, messages.S_SIMPLE_DC_VECTOR, messages.S_KESK_REQUEST, messages.S_BLAME},
+		[]Phase{PhaseLTPK, PhaseKeyExchange, PhaseDCExp, PhaseDCSimple, PhaseConfirmation, PhaseBlame, PhaseNextRun},
+	},
+	{
+		// blame after DC-EXP
+		[]uint32{messages.S_JOIN_RESPONSE, messages.S_START_DICEMIX, messages.S_KEY_EXCHANGE,
+			messages.S_KESK_REQUEST, messages.S_BLAME},
+		[]Phase{PhaseLTPK, PhaseKeyExchange, PhaseDCExp, PhaseBlame, PhaseNextRun},
+	},
+	{
+		// next run of session succeeds after blame
+		[]uint32{messages.S_JOIN_RESPONSE, messages.S_START_DICEMIX, messages.S_KEY_EXCHANGE,
+			messages.S_KESK_REQUEST, messages.S_BLAME, messages.S_START_DICEMIX, messages.S_KEY_EXCHANGE,
+			messages.S_EXP_DC_VECTOR, messages.S_SIMPLE_DC_VECTOR, messages.S_TX_SUCCESSFUL},
+		[]Phase{PhaseLTPK, PhaseKeyExchange, PhaseDCExp, PhaseBlame, PhaseNextRun,
+			PhaseKeyExchange, PhaseDCExp, PhaseDCSimple, PhaseConfirmation, PhaseDone},
+	},
+}
+
+func TestPhaseTransitions(t *testing.T) {
+	for _, test := range phaseTests {
+		var got []Phase
+		machine := newPhaseMachine(func(from, to Phase) {
+			got = append(got, to)
+		}, DefaultTimeouts())
+
+		for _, code := range test.codes {
+			next, err := machine.next(&messages.ResponseHeader{Code: code}, &utils.State{})
+			if err != nil {
+				t.Fatal("For", code, "expected", nil, "got", err)
+			}
+			machine.transition(next)
+		}
+
+		if len(got) != len(test.expected) {
+			t.Fatal("For", test.codes, "expected", test.expected, "got", got)
+		}
+		for i := range got {
+			if got[i] != test.expected[i] {
+				t.Error("For", test.codes, "expected", test.expected, "got", got)
+			}
+		}
+	}
+}
+
+func TestPhaseTransitionsCommit(t *testing.T) {
+	state := &utils.State{Pool: utils.Pool{Commit: true}}
+	codes := []uint32{messages.S_JOIN_RESPONSE, messages.S_START_DICEMIX, messages.S_KEY_EXCHANGE,
+		messages.S_EXP_DC_COMMIT, messages.S_EXP_DC_VECTOR, messages.S_SIMPLE_DC_COMMIT,
+		messages.S_SIMPLE_DC_VECTOR, messages.S_TX_SUCCESSFUL}
+	expected := []Phase{PhaseLTPK, PhaseKeyExchange, PhaseDCExpCommit, PhaseDCExp,
+		PhaseDCSimpleCommit, PhaseDCSimple, PhaseConfirmation, PhaseDone}
+
+	machine := newPhaseMachine(nil, DefaultTimeouts())
+	for i, code := range codes {
+		next, err := machine.next(&messages.ResponseHeader{Code: code}, state)
+		if err != nil || next != expected[i] {
+			t.Fatal("For", code, "expected", expected[i], "got", next, err)
+		}
+		machine.transition(next)
+	}
+
+	// vectors can't be revealed before commitments
+	machine.phase = PhaseDCExpCommit
+	if _, err := machine.next(&messages.ResponseHeader{Code: messages.S_EXP_DC_VECTOR}, state); !errors.Is(err, utils.ErrProtocolViolation) {
+		t.Error("For", messages.S_EXP_DC_VECTOR, "expected", utils.ErrProtocolViolation, "got", err)
+	}
+
+	// commitments are'nt expected in runs without them
+	machine.phase = PhaseDCExp
+	if _, err := machine.next(&messages.ResponseHeader{Code: messages.S_EXP_DC_COMMIT}, &utils.State{}); !errors.Is(err, utils.ErrProtocolViolation) {
+		t.Error("For", messages.S_EXP_DC_COMMIT, "expected", utils.ErrProtocolViolation, "got", err)
+	}
+}
+
+var unexpectedTests = []struct {
+	phase Phase
+	code  uint32
+}{
+	{PhaseJoin, messages.S_SIMPLE_DC_VECTOR},
+	{PhaseLTPK, messages.S_KEY_EXCHANGE},
+	{PhaseKeyExchange, messages.S_KESK_REQUEST},
+	{PhaseDCExp, messages.S_START_DICEMIX},
+	{PhaseDCSimple, messages.S_TX_SUCCESSFUL},
+	{PhaseConfirmation, messages.S_BLAME},
+	{PhaseBlame, messages.S_TX_SUCCESSFUL},
+	{PhaseNextRun, messages.S_KEY_EXCHANGE},
+	{PhaseDone, messages.S_JOIN_RESPONSE},
+}
+
+func TestUnexpectedResponse(t *testing.T) {
+	for _, test := range unexpectedTests {
+		machine := &phaseMachine{phase: test.phase}
+
+		_, err := machine.next(&messages.ResponseHeader{Code: test.code}, &utils.State{})
+		if !errors.Is(err, utils.ErrProtocolViolation) {
+			t.Error("For", test.phase, test.code, "expected", utils.ErrProtocolViolation, "got", err)
+		}
+	}
+}
+
+func TestSessionMismatch(t *testing.T) {
+	state := &utils.State{}
+	state.Session.SessionID = 42
+
+	machine := &phaseMachine{phase: PhaseDCExp}
+	header := &messages.ResponseHeader{Code: messages.S_EXP_DC_VECTOR, SessionId: 43}
+
+	if _, err := machine.next(header, state); !errors.Is(err, utils.ErrProtocolViolation) {
+		t.Error("For", header.SessionId, "expected", utils.ErrProtocolViolation, "got", err)
+	}
+
+	header.SessionId = 42
+	if next, err := machine.next(header, state); err != nil || next != PhaseDCSimple {
+		t.Error("For", header.SessionId, "expected", PhaseDCSimple, "got", next, err)
+	}
+}
+
+func TestPhaseTimeout(t *testing.T) {
+	timeouts := DefaultTimeouts()
+	timeouts.Phases[PhaseDCSimple] = time.Minute
+
+	machine := newPhaseMachine(nil, timeouts)
+	machine.phase = PhaseDCSimple
+	machine.received([]*messages.PeersInfo{
+		{Id: 1, MessageReceived: true},
+		{Id: 2},
+		{Id: 3},
+	})
+
+	if deadline := time.Until(machine.deadline()); deadline <= timeouts.Default || deadline > time.Minute {
+		t.Error("For", PhaseDCSimple, "expected", time.Minute, "got", deadline)
+	}
+
+	var e *utils.Error
+	err := machine.timeout(errors.New("i/o timeout"))
+	if !errors.Is(err, utils.ErrTimeout) || !errors.As(err, &e) ||
+		len(e.Peers) != 2 || e.Peers[0] != 2 || e.Peers[1] != 3 ||
+		!strings.Contains(err.Error(), PhaseDCSimple.String()) {
+		t.Error("For", PhaseDCSimple, "expected", "timeout in dc-simple by peers [2 3]", "got", err)
+	}
+
+	// peers pending in previous phase are'nt blamed for timeout of next one
+	machine.transition(PhaseConfirmation)
+	err = machine.timeout(errors.New("i/o timeout"))
+	if !errors.As(err, &e) || len(e.Peers) != 0 {
+		t.Error("For", PhaseConfirmation, "expected", "timeout without peers", "got", err)
+	}
+}
+
+func TestPartialTimeouts(t *testing.T) {
+	tests := []struct {
+		timeouts Timeouts
+		phase    Phase
+		expected time.Duration
+	}{
+		// only session budget configured
+		{Timeouts{Session: time.Minute}, PhaseDCExp, utils.ResponseWait * time.Second},
+		{Timeouts{Default: time.Minute}, PhaseDCExp, time.Minute},
+		{Timeouts{Phases: map[Phase]time.Duration{PhaseDCExp: time.Hour}}, PhaseDCExp, time.Hour},
+		{Timeouts{Phases: map[Phase]time.Duration{PhaseDCExp: 0}, Default: time.Minute}, PhaseDCExp, time.Minute},
+	}
+
+	for _, test := range tests {
+		if timeout := test.timeouts.phase(test.phase); timeout != test.expected {
+			t.Error("For", test.timeouts, "expected", test.expected, "got", timeout)
+		}
+	}
+
+	// run is'nt timed out immediately
+	machine := newPhaseMachine(nil, Timeouts{Session: time.Minute})
+	if deadline := time.Until(machine.deadline()); deadline <= 0 {
+		t.Error("For", "session budget only", "expected", time.Minute, "got", deadline)
+	}
+}
+
+func TestSessionBudget(t *testing.T) {
+	timeouts := DefaultTimeouts()
+	timeouts.Session = time.Second
+
+	machine := newPhaseMachine(nil, timeouts)
+	if deadline := time.Until(machine.deadline()); deadline > time.Second {
+		t.Error("For", timeouts.Session, "expected", time.Second, "got", deadline)
+	}
+
+	machine.started = time.Now().Add(-2 * time.Second)
+	err := machine.timeout(errors.New("i/o timeout"))
+	if !errors.Is(err, utils.ErrTimeout) || !strings.Contains(err.Error(), "session budget") {
+		t.Error("For", timeouts.Session, "expected", "session budget exceeded", "got", err)
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// errConnectionLost - connection with server dropped during run
+var errConnectionLost = errors.New("connection lost")
+
+// Reconnect - policy to reconnect after connection with server drops
+type Reconnect struct {
+	// Attempts - maximum number of reconnection attempts, 0 disables reconnection
+	Attempts int
+	// Backoff - delay before first attempt, doubled after every attempt
+	Backoff time.Duration
+	// MaxBackoff - upper bound of delay between attempts
+	MaxBackoff time.Duration
+	// ResumeWindow - time since drop within which session can be resumed
+	// after that fresh session is joined
+	ResumeWindow time.Duration
+}
+
+// DefaultReconnect - retries 5 times starting from 1 second delay
+// session can be resumed within ResponseWait seconds
+func DefaultReconnect() Reconnect {
+	return Reconnect{
+		Attempts:     5,
+		Backoff:      time.Second,
+		MaxBackoff:   16 * time.Second,
+		ResumeWindow: utils.ResponseWait * time.Second,
+	}
+}
+
+// SetReconnect - configures reconnection policy of run
+func (c *connection) SetReconnect(reconnect Reconnect) {
+	c.reconnect = reconnect
+}
+
+// reconnects to server with exponential backoff
+// resumes current session over new connection if possible
+// otherwise resets run to join fresh session
+func (c *connection) reconnectLink(ctx context.Context, link *link, state *utils.State, machine *phaseMachine) error {
+	dropped := time.Now()
+	delay := c.reconnect.Backoff
+
+	for attempt := 1; attempt <= c.reconnect.Attempts; attempt++ {
+		c.logger.Info("Reconnecting in ", delay, ", attempt ", attempt)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > c.reconnect.MaxBackoff {
+			delay = c.reconnect.MaxBackoff
+		}
+
+		conn, err := c.connect(ctx)
+		if errors.Is(err, utils.ErrPinMismatch) {
+			return err
+		}
+		if err != nil {
+			c.logger.Info("Unable to reconnect - ", err)
+			continue
+		}
+
+		// session is not established yet or it is too late to resume
+		if machine.phase <= PhaseLTPK || time.Since(dropped) > c.reconnect.ResumeWindow {
+			c.logger.Info("Joining fresh session")
+			rejoin(state, machine)
+			link.Transport, link.last = conn, nil
+			return nil
+		}
+
+		if err = resume(conn, link.last, state, machine); err == nil {
+			c.logger.Info("Resumed session - ", state.Session.SessionID)
+			link.Transport = conn
+			return nil
+		}
+
+		conn.Close()
+		c.logger.Info("Unable to resume session - ", err)
+
+		// server refused to resume
+		// fresh session would be joined in next attempt
+		if errors.Is(err, utils.ErrServer) {
+			rejoin(state, machine)
+		}
+	}
+
+	return fmt.Errorf("%w: unable to reconnect after %d attempts", errConnectionLost, c.reconnect.Attempts)
+}
+
+// re-authenticates with server using our LTSK
+// and requests to resume current session
+// resends last request as server could have missed it
+func resume(conn transport.Transport, last []byte, state *utils.State, machine *phaseMachine) error {
+	header := requestHeader(messages.C_RESUME_REQUEST, state.Session.SessionID, state.Session.MyID, state.Run)
+	message, err := proto.Marshal(&messages.ResumeRequest{
+		Header:   header,
+		LastCode: machine.last,
+	})
+	if err != nil {
+		return err
+	}
+
+	// generate signed message using our ltsk
+	resumeRequest, err := generateSignedRequest(state.Session.Ltsk, message)
+	if err != nil {
+		return err
+	}
+
+	if err = conn.Send(resumeRequest); err != nil {
+		return fmt.Errorf("%w: %v", errConnectionLost, err)
+	}
+
+	response := &messages.ResumeResponse{}
+	if err = readResponse(conn, messages.S_RESUME_RESPONSE, machine.deadline(), response); err != nil {
+		return err
+	}
+	if err = serverError(response.Header); err != nil {
+		return err
+	}
+	if response.Header.SessionId != state.Session.SessionID {
+		return utils.NewError(utils.ErrProtocolViolation,
+			"resumed session %d, active session %d", response.Header.SessionId, state.Session.SessionID)
+	}
+
+	// server reports status of phase we resumed in
+	machine.received(response.Peers)
+
+	if last != nil {
+		if err = conn.Send(last); err != nil {
+			return fmt.Errorf("%w: %v", errConnectionLost, err)
+		}
+	}
+
+	return nil
+}
+
+// reads response from server into response
+// if it has expected code
+func readResponse(conn transport.Transport, code uint32, deadline time.Time, response proto.Message) error {
+	message, err := transport.ReceiveDeadline(conn, deadline)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errConnectionLost, err)
+	}
+
+	generic := &messages.GenericResponse{}
+	if err = proto.Unmarshal(message, generic); err != nil || generic.Header == nil {
+		return utils.NewError(utils.ErrProtocolViolation, "malformed response: %v", err)
+	}
+
+	if generic.Header.Code != code {
+		return utils.NewError(utils.ErrProtocolViolation,
+			"expected response with code %d, got %d", code, generic.Header.Code)
+	}
+
+	return unmarshal(message, response)
+}
+
+// resets run and phase machine to join fresh session
+func rejoin(state *utils.State, machine *phaseMachine) {
+	resetRun(state)
+	machine.last = 0
+	machine.transition(PhaseJoin)
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+)
+
+// sends response to client
+func writeResponse(conn *websocket.Conn, response proto.Message) {
+	message, _ := proto.Marshal(response)
+	conn.WriteMessage(websocket.BinaryMessage, message)
+}
+
+func joinResponse(id int32) *messages.RegisterResponse {
+	return &messages.RegisterResponse{
+		Header: &messages.ResponseHeader{Code: messages.S_JOIN_RESPONSE},
+		Id:     id,
+	}
+}
+
+func TestResume(t *testing.T) {
+	state := &utils.State{}
+	state.Session.Ltpk, state.Session.Ltsk, _ = ecdsa.NewCurveECDSA().GenerateKeyPair()
+	state.Session.SessionID = 42
+	state.Session.MyID = 3
+
+	machine := newPhaseMachine(nil, DefaultTimeouts())
+	machine.phase = PhaseDCExp
+	machine.last = messages.S_KEY_EXCHANGE
+	last := []byte("last request")
+
+	resent := make(chan []byte, 1)
+	srv, c := newTestServer(func(conn *websocket.Conn) {
+		// resume request should be signed using our LTSK
+		_, message, _ := conn.ReadMessage()
+		signedRequest := &messages.SignedRequest{}
+		request := &messages.ResumeRequest{}
+		proto.Unmarshal(message, signedRequest)
+		proto.Unmarshal(signedRequest.RequestData, request)
+
+		response := &messages.ResumeResponse{
+			Header: &messages.ResponseHeader{Code: messages.S_RESUME_RESPONSE, SessionId: 42},
+			Peers:  []*messages.PeersInfo{{Id: 1, MessageReceived: true}, {Id: 2}, {Id: 3, MessageReceived: true}},
+		}
+		if !ecdsa.NewCurveECDSA().Verify(state.Session.Ltpk, signedRequest.RequestData, signedRequest.Signature) ||
+			request.Header.SessionId != 42 || request.Header.Id != 3 || request.LastCode != messages.S_KEY_EXCHANGE {
+			response.Header.Err = "invalid resume request"
+		}
+		writeResponse(conn, response)
+
+		_, message, _ = conn.ReadMessage()
+		resent <- message
+	})
+	defer srv.Close()
+
+	conn, err := c.connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err = resume(conn, last, state, machine); err != nil {
+		t.Fatal("For", "resume", "expected", nil, "got", err)
+	}
+
+	if message := <-resent; !bytes.Equal(message, last) {
+		t.Error("For", "resume", "expected", last, "got", message)
+	}
+
+	// status reported on resume names peers pending in current phase
+	if len(machine.pending) != 1 || machine.pending[0] != 2 {
+		t.Error("For", "resume", "expected", []int32{2}, "got", machine.pending)
+	}
+}
+
+func TestResumeRefused(t *testing.T) {
+	state := &utils.State{}
+	state.Session.Ltpk, state.Session.Ltsk, _ = ecdsa.NewCurveECDSA().GenerateKeyPair()
+	state.Session.SessionID = 42
+
+	srv, c := newTestServer(func(conn *websocket.Conn) {
+		conn.ReadMessage()
+		writeResponse(conn, &messages.ResumeResponse{
+			Header: &messages.ResponseHeader{Code: messages.S_RESUME_RESPONSE, Err: "session expired"},
+		})
+	})
+	defer srv.Close()
+
+	conn, err := c.connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	machine := newPhaseMachine(nil, DefaultTimeouts())
+	if err = resume(conn, nil, state, machine); !errors.Is(err, utils.ErrServer) {
+		t.Error("For", "refused resume", "expected", utils.ErrServer, "got", err)
+	}
+}
+
+func TestReconnectRejoin(t *testing.T) {
+	// first connection drops right after join
+	// client should join again over new connection
+	var connections int32
+	rejoined := make(chan struct{})
+	srv, c := newTestServer(func(conn *websocket.Conn) {
+		n := atomic.AddInt32(&connections, 1)
+
+		// join request
+		conn.ReadMessage()
+		writeResponse(conn, joinResponse(n))
+		if n == 1 {
+			return
+		}
+
+		// LTPK request over new connection
+		conn.ReadMessage()
+		close(rejoined)
+		conn.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.SetReconnect(Reconnect{Attempts: 3, Backoff: 10 * time.Millisecond, MaxBackoff: time.Second})
+
+	state := &utils.State{}
+	state.Session.Ltpk, state.Session.Ltsk, _ = ecdsa.NewCurveECDSA().GenerateKeyPair()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- c.RegisterContext(ctx, state)
+	}()
+
+	select {
+	case <-rejoined:
+	case <-time.After(5 * time.Second):
+		t.Fatal("For", "dropped connection", "expected", "rejoin", "got", "no rejoin")
+	}
+
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Error("For", "cancelled run", "expected", context.Canceled, "got", err)
+	}
+	if state.Session.MyID != 2 {
+		t.Error("For", "rejoin", "expected", 2, "got", state.Session.MyID)
+	}
+}
@@ -1,10 +1,28 @@
 package server
 
 import (
+	"context"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/coinjoin"
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
 	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // Server - The main interface to enable connection with server.
 type Server interface {
-	Register(*utils.State)
+	Register(*utils.State) error
+	RegisterContext(context.Context, *utils.State) error
+	OnTransition(func(from, to Phase))
+	OnEvent(func(Event))
+	OnResponse(func(code uint32, latency time.Duration))
+	SetTimeouts(Timeouts)
+	SetReconnect(Reconnect)
+	SetTLS(TLS) error
+	SetDialer(transport.Dialer)
+	SetFreshMessages(func(run uint32) ([][]byte, error))
+	SetUTXOs(coinjoin.UTXOSource)
+	SetLogger(log.FieldLogger)
 }
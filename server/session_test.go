@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdh"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gorilla/websocket"
+)
+
+// peer simulated by test server
+type testPeer struct {
+	id      int32
+	ltpk    []byte
+	ltsk    []byte
+	kepk    []byte
+	numMsgs uint32
+}
+
+func newTestPeer(id int32, numMsgs uint32) testPeer {
+	ltpk, ltsk, _ := ecdsa.NewCurveECDSA().GenerateKeyPair()
+	ecdh := ecdh.NewCurve25519ECDH()
+	_, kepk, _ := ecdh.GenerateKeyPair()
+
+	return testPeer{
+		id:      id,
+		ltpk:    ltpk,
+		ltsk:    ltsk,
+		kepk:    ecdh.Marshal(kepk),
+		numMsgs: numMsgs,
+	}
+}
+
+// returns KEPK of peer relayed by server along with its signed request
+func (p testPeer) keyExchange(sessionID uint64) *messages.PeersInfo {
+	message, _ := proto.Marshal(&messages.KeyExchangeRequest{
+		Header:    requestHeader(messages.C_KEY_EXCHANGE, sessionID, p.id, 1),
+		PublicKey: p.kepk,
+		NumMsgs:   p.numMsgs,
+	})
+	signedRequest, _ := generateSignedRequest(p.ltsk, message)
+
+	return &messages.PeersInfo{
+		Id:              p.id,
+		PublicKey:       p.kepk,
+		NumMsgs:         p.numMsgs,
+		MessageReceived: true,
+		SignedRequests:  [][]byte{signedRequest},
+	}
+}
+
+// drives client through join, key exchange and DC-EXP
+// along with two simulated peers, then aborts run
+// returns length of DC-EXP vector sent by client
+func scriptedRun(conn *websocket.Conn, sessionID uint64, dcExpLength chan<- int) {
+	peers := []testPeer{newTestPeer(2, 1), newTestPeer(3, 2)}
+
+	// C_JOIN_REQUEST
+	signedRequest := &messages.SignedRequest{}
+	join := &messages.JoinRequest{}
+	_, message, _ := conn.ReadMessage()
+	proto.Unmarshal(message, signedRequest)
+	proto.Unmarshal(signedRequest.RequestData, join)
+	writeResponse(conn, joinResponse(1))
+
+	// C_LTPK_REQUEST
+	ltpk := &messages.LtpkExchangeRequest{}
+	_, message, _ = conn.ReadMessage()
+	proto.Unmarshal(message, signedRequest)
+	proto.Unmarshal(signedRequest.RequestData, ltpk)
+
+	start := &messages.DiceMixResponse{
+		Header:       &messages.ResponseHeader{Code: messages.S_START_DICEMIX, SessionId: sessionID},
+		Denomination: join.Denomination,
+		Version:      join.Version,
+		SlotSize:     join.SlotSize,
+		Run:          1,
+		Peers:        []*messages.PeersInfo{{Id: 1, LTPublicKey: ltpk.PublicKey, NumMsgs: join.NumMsgs}},
+	}
+	for _, peer := range peers {
+		start.Peers = append(start.Peers, &messages.PeersInfo{Id: peer.id, LTPublicKey: peer.ltpk})
+	}
+	writeResponse(conn, start)
+
+	// C_KEY_EXCHANGE
+	conn.ReadMessage()
+	keyExchange := &messages.DiceMixResponse{
+		Header: &messages.ResponseHeader{Code: messages.S_KEY_EXCHANGE, SessionId: sessionID},
+	}
+	for _, peer := range peers {
+		keyExchange.Peers = append(keyExchange.Peers, peer.keyExchange(sessionID))
+	}
+	writeResponse(conn, keyExchange)
+
+	// C_EXP_DC_VECTOR
+	dcExp := &messages.DCExpRequest{}
+	_, message, _ = conn.ReadMessage()
+	proto.Unmarshal(message, signedRequest)
+	proto.Unmarshal(signedRequest.RequestData, dcExp)
+	dcExpLength <- len(dcExp.DCExpVector)
+
+	writeResponse(conn, &messages.DCExpResponse{
+		Header: &messages.ResponseHeader{Code: messages.S_EXP_DC_VECTOR, SessionId: sessionID, Err: "run aborted"},
+	})
+	conn.ReadMessage()
+}
+
+func TestConcurrentSessions(t *testing.T) {
+	const sessions = 8
+
+	var wg sync.WaitGroup
+	for i := 0; i < sessions; i++ {
+		wg.Add(1)
+		go func(sessionID uint64) {
+			defer wg.Done()
+
+			dcExpLength := make(chan int, 1)
+			srv, c := newTestServer(func(conn *websocket.Conn) {
+				scriptedRun(conn, sessionID, dcExpLength)
+			})
+			defer srv.Close()
+
+			state := &utils.State{
+				Pool:           utils.Pool{Denomination: 100000, MinPeers: 3, SlotSize: dc.DefaultSlotSize},
+				MyMsgCount:     2,
+				MyMessages:     make([]string, 2),
+				MyMessagesHash: make([]uint64, 2),
+			}
+			state.Session.Ltpk, state.Session.Ltsk, _ = ecdsa.NewCurveECDSA().GenerateKeyPair()
+
+			err := c.RegisterContext(context.Background(), state)
+
+			var e *utils.Error
+			if !errors.Is(err, utils.ErrServer) || !errors.As(err, &e) || e.Code != messages.S_EXP_DC_VECTOR {
+				t.Error("For", sessionID, "expected", "server error in DC-EXP", "got", err)
+			}
+			if state.Session.SessionID != sessionID {
+				t.Error("For", sessionID, "expected", sessionID, "got", state.Session.SessionID)
+			}
+			// power sums of all 5 messages of run
+			if length := <-dcExpLength; length != 5 {
+				t.Error("For", sessionID, "expected", 5, "got", length)
+			}
+		}(uint64(i + 1))
+	}
+
+	wg.Wait()
+}
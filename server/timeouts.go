@@ -0,0 +1,40 @@
+package server
+
+import (
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+// Timeouts - read deadlines for DiceMix run
+// zero values are'nt limits, so partly filled Timeouts are usable
+type Timeouts struct {
+	// Phases - time to wait for server response in particular phase
+	// 0 waits Default
+	Phases map[Phase]time.Duration
+	// Default - time to wait in phases not present in Phases
+	// 0 waits ResponseWait seconds
+	Default time.Duration
+	// Session - overall time budget of run, 0 for no limit
+	Session time.Duration
+}
+
+// DefaultTimeouts - waits ResponseWait seconds in every phase
+// without any limit on overall run
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Phases:  make(map[Phase]time.Duration),
+		Default: utils.ResponseWait * time.Second,
+	}
+}
+
+// returns time to wait for server response in phase
+func (t Timeouts) phase(p Phase) time.Duration {
+	if timeout := t.Phases[p]; timeout > 0 {
+		return timeout
+	}
+	if t.Default > 0 {
+		return t.Default
+	}
+	return utils.ResponseWait * time.Second
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+// TLS - configuration of secure (wss://) connection with server
+type TLS struct {
+	// CAFile - PEM bundle of CA's trusted to verify server certificate
+	// system roots are used if empty
+	CAFile string
+	// CertFile, KeyFile - optional client certificate presented to server
+	CertFile string
+	KeyFile  string
+	// Pins - base64 encoded SHA-256 of SubjectPublicKeyInfo's of server certificate
+	// pinning is disabled if empty
+	Pins []string
+}
+
+// SetTLS - enables wss:// connection with server
+func (c *connection) SetTLS(config TLS) error {
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	c.tls = tlsConfig
+	return nil
+}
+
+// Config - builds tls.Config for transport dialed via SetDialer
+// server certificate should be issued for serverName
+func (t TLS) Config(serverName string) (*tls.Config, error) {
+	config, err := t.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	config.ServerName = serverName
+	return config, nil
+}
+
+// builds tls.Config from files provided
+func (t TLS) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{}
+
+	if t.CAFile != "" {
+		bundle, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", t.CAFile)
+		}
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(t.Pins) != 0 {
+		pins := make(map[string]bool, len(t.Pins))
+		for _, pin := range t.Pins {
+			pins[pin] = true
+		}
+
+		// invoked after certificate chain is verified
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyPins(rawCerts, pins)
+		}
+	}
+
+	return config, nil
+}
+
+// checks if SPKI of server leaf certificate matches one of pins
+func verifyPins(rawCerts [][]byte, pins map[string]bool) error {
+	if len(rawCerts) == 0 {
+		return utils.NewError(utils.ErrPinMismatch, "server presented no certificate")
+	}
+
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return utils.NewError(utils.ErrPinMismatch, "invalid server certificate: %w", err)
+	}
+
+	if pin := spkiPin(cert); !pins[pin] {
+		return utils.NewError(utils.ErrPinMismatch, "server certificate SPKI %s is not pinned", pin)
+	}
+
+	return nil
+}
+
+// returns base64 encoded SHA-256 of SubjectPublicKeyInfo of certificate
+func spkiPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dev-appmonsters/dicemix-light-client/transport"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/gorilla/websocket"
+)
+
+// starts TLS websocket server which accepts connections
+// returns client connection pointing to it
+// and path of PEM bundle containing its certificate
+func newTLSTestServer(t *testing.T, clientAuth tls.ClientAuthType) (*httptest.Server, *connection, string) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	srv.TLS = &tls.Config{ClientAuth: clientAuth}
+	srv.StartTLS()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, bundle, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return srv, NewConnection(strings.TrimPrefix(srv.URL, "https://")).(*connection), caFile
+}
+
+// generates self signed client certificate
+// returns paths of certificate and key files
+func clientCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dicemix client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+
+	return certFile, keyFile
+}
+
+func dialTLS(c *connection, config TLS) error {
+	if err := c.SetTLS(config); err != nil {
+		return err
+	}
+
+	conn, err := c.connect(context.Background())
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return nil
+}
+
+func TestTLSPinning(t *testing.T) {
+	srv, c, caFile := newTLSTestServer(t, tls.NoClientCert)
+	defer srv.Close()
+
+	pin := spkiPin(srv.Certificate())
+
+	if err := dialTLS(c, TLS{CAFile: caFile, Pins: []string{pin}}); err != nil {
+		t.Error("For", pin, "expected", nil, "got", err)
+	}
+
+	if err := dialTLS(c, TLS{CAFile: caFile}); err != nil {
+		t.Error("For", "no pins", "expected", nil, "got", err)
+	}
+
+	// certificate is trusted but not pinned
+	other := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	if err := dialTLS(c, TLS{CAFile: caFile, Pins: []string{other}}); !errors.Is(err, utils.ErrPinMismatch) {
+		t.Error("For", other, "expected", utils.ErrPinMismatch, "got", err)
+	}
+
+	// untrusted certificate is'nt a pinning failure
+	if err := dialTLS(c, TLS{Pins: []string{pin}}); err == nil || errors.Is(err, utils.ErrPinMismatch) {
+		t.Error("For", "untrusted certificate", "expected", "verification error", "got", err)
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	srv, c, caFile := newTLSTestServer(t, tls.RequireAnyClientCert)
+	defer srv.Close()
+
+	if err := dialTLS(c, TLS{CAFile: caFile}); err == nil {
+		t.Error("For", "no client certificate", "expected", "handshake error", "got", err)
+	}
+
+	certFile, keyFile := clientCertificate(t)
+	if err := dialTLS(c, TLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}); err != nil {
+		t.Error("For", certFile, "expected", nil, "got", err)
+	}
+
+	if _, err := (TLS{CAFile: filepath.Join(os.TempDir(), "missing.pem")}).tlsConfig(); err == nil {
+		t.Error("For", "missing CA bundle", "expected", "error", "got", err)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	srv, _, caFile := newTLSTestServer(t, tls.NoClientCert)
+	defer srv.Close()
+
+	pin := spkiPin(srv.Certificate())
+	other := "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+
+	tests := []struct {
+		config     TLS
+		serverName string
+		expected   bool
+	}{
+		{TLS{CAFile: caFile, Pins: []string{pin}}, "127.0.0.1", true},
+		{TLS{CAFile: caFile}, "example.com", true},
+		// certificate is'nt issued for server name
+		{TLS{CAFile: caFile}, "dicemix.org", false},
+		{TLS{CAFile: caFile, Pins: []string{other}}, "127.0.0.1", false},
+	}
+
+	for _, test := range tests {
+		config, err := test.config.Config(test.serverName)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// TLS connection dialed over TCP as by tcp transport
+		conn, err := transport.DialTCP(context.Background(), srv.Listener.Addr().String(), config)
+		if (err == nil) != test.expected {
+			t.Error("For", test.serverName, test.config.Pins, "expected", test.expected, "got", err)
+		}
+		if err == nil {
+			conn.Close()
+		}
+	}
+}
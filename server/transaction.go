@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/dev-appmonsters/dicemix-light-client/coinjoin"
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/logging"
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+)
+
+// assembles coinjoin transaction from messages resolved in DC-SIMPLE
+// and inputs announced by peers in KeyExchange, then signs our inputs
+// returns false if they does'nt form valid transaction
+func (c *connection) signTransaction(state *utils.State) ([]utils.Signature, bool, error) {
+	state.Transaction = nil
+
+	tx, err := coinjoin.FromState(state)
+	if err == nil {
+		state.Transaction, err = coinjoin.Serialize(tx)
+	}
+	if err != nil {
+		c.logger.Info("Unable to assemble transaction - ", err)
+		return nil, false, nil
+	}
+
+	c.logger.Info("Transaction hash - ", logging.Transaction(tx.TxHash()))
+
+	// amounts announced by peers are'nt committed to by P2PKH signatures
+	// failure of source itself is'nt caused by peers
+	if c.utxos != nil {
+		var e *utils.Error
+		if err = coinjoin.VerifyUTXOs(state, c.utxos); errors.As(err, &e) {
+			c.logger.Info("Inputs of peers does'nt match UTXOs - ", err)
+			state.Transaction = nil
+			return nil, false, nil
+		} else if err != nil {
+			return nil, false, err
+		}
+	}
+
+	// our inputs should be signable, nothing peers can cause
+	signatures, err := coinjoin.Sign(tx, state.MyInputs)
+	if err != nil {
+		return nil, false, utils.NewError(utils.ErrCrypto, "unable to sign inputs: %v", err)
+	}
+	return signatures, true, nil
+}
+
+// verifies transaction signed by peers and assembled by server
+// every peer should sign all of its inputs and transaction should pay to our outputs
+// stores final transaction in state
+func (c *connection) verifyTransaction(state *utils.State, response *messages.TXDoneResponse) error {
+	// signatures relayed by server should be signed by peers
+	if err := verifyPeersInfo(state, response.Peers, messages.C_TX_CONFIRMATION); err != nil {
+		return err
+	}
+
+	unsigned, err := coinjoin.Deserialize(state.Transaction)
+	if err != nil {
+		return err
+	}
+
+	signatures := make(map[int32][]utils.Signature)
+	for _, peer := range response.Peers {
+		signatures[peer.Id] = coinjoin.DecodeSignatures(peer.Signatures)
+	}
+	for _, peer := range state.Peers {
+		if _, ok := signatures[peer.ID]; !ok {
+			return utils.NewError(utils.ErrProtocolViolation, "signatures of peer %d are missing", peer.ID)
+		}
+		if err := coinjoin.VerifySignatures(unsigned, peer.Inputs, signatures[peer.ID]); err != nil {
+			return utils.NewPeerError([]int32{peer.ID}, "invalid signatures: %v", err)
+		}
+	}
+
+	// final transaction should be fully signed and contain our outputs
+	inputs, err := coinjoin.Inputs(state)
+	if err != nil {
+		return err
+	}
+	outputs, err := myOutputs(state)
+	if err != nil {
+		return err
+	}
+
+	final, err := coinjoin.Deserialize(response.Transaction)
+	if err == nil {
+		err = coinjoin.Verify(final, unsigned, inputs, outputs, state.Pool.Denomination)
+	}
+	if err != nil {
+		return utils.NewError(utils.ErrProtocolViolation, "invalid transaction: %v", err)
+	}
+
+	state.Transaction = response.Transaction
+	return nil
+}
+
+// decodes output scripts from our messages
+func myOutputs(state *utils.State) ([][]byte, error) {
+	slots := make([][]byte, len(state.MyMessages))
+	for i, message := range state.MyMessages {
+		slots[i] = utils.Base58StringToBytes(message)
+	}
+	return dc.Unpack(slots)
+}
@@ -0,0 +1,365 @@
+package server
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// verifies peers broadcasts relayed by server
+// every peer should include SignedRequest's with codes provided
+// signed using LTSK corresponding to LTPK it announced,
+// contents of which should match with peers info sent by server
+// returns error blaming peers whose broadcasts are not valid
+// every peer of run should be relayed, as DC vectors padded without
+// pads of peer left out let that peer and server unmask our slots
+func verifyPeersInfo(state *utils.State, peers []*messages.PeersInfo, codes ...uint32) error {
+	if err := verifyRelayed(state, peers); err != nil {
+		return err
+	}
+
+	// map peers info verified in previous stages to their ids
+	var peerIDs = make(map[int32]utils.Peers)
+	for _, peer := range state.Peers {
+		peerIDs[peer.ID] = peer
+	}
+
+	keyExchange := false
+	for _, code := range codes {
+		keyExchange = keyExchange || code == messages.C_KEY_EXCHANGE
+	}
+
+	for _, peer := range peers {
+		// filterPeers excludes clients which are not our peers
+		stored, ok := peerIDs[peer.Id]
+		if !ok {
+			continue
+		}
+
+		if len(peer.LTPublicKey) != 0 && !bytes.Equal(peer.LTPublicKey, stored.LTPubKey) {
+			return utils.NewPeerError([]int32{peer.Id}, "LTPK changed during run")
+		}
+
+		// KEPK and number of messages can only be changed via KeyExchange
+		if !keyExchange && (len(peer.PublicKey) != 0 && !bytes.Equal(peer.PublicKey, stored.PubKey) ||
+			peer.NumMsgs != 0 && peer.NumMsgs != stored.NumMsgs) {
+			return utils.NewPeerError([]int32{peer.Id}, "KEPK changed outside KeyExchange")
+		}
+
+		// every vector relayed by server should be signed by peer
+		required := append([]uint32{}, codes...)
+		if len(peer.DCVector) != 0 {
+			required = append(required, messages.C_EXP_DC_VECTOR)
+		}
+		if len(peer.DCSimpleVector) != 0 {
+			required = append(required, messages.C_SIMPLE_DC_VECTOR)
+		}
+		if len(peer.PrivateKey) != 0 {
+			required = append(required, messages.C_KESK_RESPONSE)
+		}
+
+		verified := make(map[uint32]bool)
+		for _, signedRequest := range peer.SignedRequests {
+			code, err := verifySignedRequest(state, peer, stored.LTPubKey, signedRequest)
+			if err != nil {
+				return err
+			}
+			verified[code] = true
+		}
+
+		for _, code := range required {
+			if !verified[code] {
+				return utils.NewPeerError([]int32{peer.Id}, "missing signed request with code %d", code)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checks if server relayed every peer of run exactly once
+// returns error naming peers left out or duplicated
+func verifyRelayed(state *utils.State, peers []*messages.PeersInfo) error {
+	relayed := make(map[int32]int, len(peers))
+	for _, peer := range peers {
+		relayed[peer.Id]++
+	}
+
+	missing := make([]int32, 0)
+	duplicate := make([]int32, 0)
+	for _, peer := range state.Peers {
+		switch relayed[peer.ID] {
+		case 0:
+			missing = append(missing, peer.ID)
+		case 1:
+		default:
+			duplicate = append(duplicate, peer.ID)
+		}
+	}
+
+	if len(missing) != 0 {
+		return utils.NewError(utils.ErrProtocolViolation, "server did'nt relay peers %v", missing)
+	}
+	if len(duplicate) != 0 {
+		return utils.NewError(utils.ErrProtocolViolation, "server relayed peers %v more than once", duplicate)
+	}
+	return nil
+}
+
+// checks if peers use KEPK's they announced in DC-SIMPLE of previous run
+// KEPK's expected are stored as PubKey of peers once next run starts
+func verifyNextKeys(state *utils.State, peers []*messages.PeersInfo) error {
+	var peerIDs = make(map[int32]utils.Peers)
+	for _, peer := range state.Peers {
+		peerIDs[peer.ID] = peer
+	}
+
+	for _, peer := range peers {
+		stored, ok := peerIDs[peer.Id]
+		if !ok || len(stored.PubKey) == 0 {
+			continue
+		}
+		if !bytes.Equal(peer.PublicKey, stored.PubKey) {
+			return utils.NewPeerError([]int32{peer.Id}, "KEPK does'nt match one announced in previous run")
+		}
+	}
+
+	return nil
+}
+
+// checks if DC vectors revealed by peers match commitments they sent before
+// code - C_EXP_DC_VECTOR or C_SIMPLE_DC_VECTOR revealed
+// returns error blaming every peer whose vector does'nt match, nothing in runs without commitments
+func verifyCommitments(state *utils.State, code uint32) error {
+	if !state.Pool.Commit {
+		return nil
+	}
+
+	mismatched := make([]int32, 0)
+	for _, peer := range state.Peers {
+		var commitment, expected []byte
+		if code == messages.C_EXP_DC_VECTOR {
+			commitment = peer.DCExpCommitment
+			expected = dc.CommitDCExp(state.Session.SessionID, state.Run, peer.ID, peer.DCVector)
+		} else {
+			commitment = peer.DCSimpleCommitment
+			expected = dc.CommitDCSimple(state.Session.SessionID, state.Run, peer.ID, peer.DCSimpleVector)
+		}
+
+		if !bytes.Equal(commitment, expected) {
+			mismatched = append(mismatched, peer.ID)
+		}
+	}
+
+	if len(mismatched) != 0 {
+		return utils.NewPeerError(mismatched, "revealed DC vector does'nt match commitment")
+	}
+	return nil
+}
+
+// checks if run started by server honours
+// pool parameters we requested in C_JOIN_REQUEST
+func verifyPool(state *utils.State, response *messages.DiceMixResponse) error {
+	if response.Version != utils.ProtocolVersion {
+		return utils.NewError(utils.ErrProtocolViolation,
+			"run uses protocol version %d, expected %d", response.Version, utils.ProtocolVersion)
+	}
+
+	if response.Denomination != state.Pool.Denomination {
+		return utils.NewError(utils.ErrProtocolViolation,
+			"run has denomination %d, requested %d", response.Denomination, state.Pool.Denomination)
+	}
+
+	if response.SlotSize != state.Pool.SlotSize {
+		return utils.NewError(utils.ErrProtocolViolation,
+			"run has slots of %d bytes, requested %d", response.SlotSize, state.Pool.SlotSize)
+	}
+
+	if response.Commit != state.Pool.Commit {
+		return utils.NewError(utils.ErrProtocolViolation,
+			"run has commitments set to %t, requested %t", response.Commit, state.Pool.Commit)
+	}
+
+	if uint32(len(response.Peers)) < state.Pool.MinPeers {
+		return utils.NewError(utils.ErrProtocolViolation,
+			"run has anonymity set of %d, requested at least %d", len(response.Peers), state.Pool.MinPeers)
+	}
+
+	// server echoes number of messages it placed us with
+	for _, peer := range response.Peers {
+		if peer.Id != state.Session.MyID {
+			continue
+		}
+		if peer.NumMsgs != state.MyMsgCount {
+			return utils.NewError(utils.ErrProtocolViolation,
+				"run placed us with %d messages, requested %d", peer.NumMsgs, state.MyMsgCount)
+		}
+		return nil
+	}
+
+	return utils.NewError(utils.ErrProtocolViolation, "run does'nt include us")
+}
+
+// checks if roots sent by server are same as roots solved locally
+// server may send them in any order, slots are taken from order of
+// roots solved locally, so that permutation sent by server to one peer
+// can't place its message in slot identifying it
+func verifyRoots(roots, serverRoots []uint64) error {
+	sorted := make([]uint64, len(serverRoots))
+	copy(sorted, serverRoots)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// roots solved locally are sorted in ascending order
+	if !equalVectors(roots, sorted) {
+		return utils.NewError(utils.ErrProtocolViolation, "roots calculated by server does'nt match with roots solved locally")
+	}
+	return nil
+}
+
+// verifies signature of request using ltpk of peer
+// and checks if its contents match with peers info
+// returns code of request
+func verifySignedRequest(state *utils.State, peer *messages.PeersInfo, ltpk, data []byte) (uint32, error) {
+	invalid := func(code uint32) (uint32, error) {
+		return code, utils.NewPeerError([]int32{peer.Id}, "invalid signed request with code %d", code)
+	}
+
+	signedRequest := &messages.SignedRequest{}
+	if err := proto.Unmarshal(data, signedRequest); err != nil {
+		return invalid(0)
+	}
+
+	request := &messages.GenericRequest{}
+	if err := proto.Unmarshal(signedRequest.RequestData, request); err != nil || request.Header == nil {
+		return invalid(0)
+	}
+	code := request.Header.Code
+
+	ecdsa := ecdsa.NewCurveECDSA()
+	if !ecdsa.Verify(ltpk, signedRequest.RequestData, signedRequest.Signature) {
+		return invalid(code)
+	}
+
+	// request should be sent by peer in current session
+	if request.Header.Id != peer.Id || request.Header.SessionId != state.Session.SessionID {
+		return invalid(code)
+	}
+
+	// peer signed request in other run of session, server replayed it
+	// to get peer blamed for vectors or keys it did'nt send in this run
+	if request.Header.Run != state.Run {
+		return code, utils.NewError(utils.ErrProtocolViolation,
+			"request with code %d of peer %d signed in run %d replayed in run %d", code, peer.Id, request.Header.Run, state.Run)
+	}
+
+	if !matchPeerInfo(code, signedRequest.RequestData, peer) {
+		return invalid(code)
+	}
+	return code, nil
+}
+
+// checks if contents of request sent by peer
+// match with peers info relayed by server
+func matchPeerInfo(code uint32, data []byte, peer *messages.PeersInfo) bool {
+	switch code {
+	case messages.C_KEY_EXCHANGE:
+		request := &messages.KeyExchangeRequest{}
+		if err := proto.Unmarshal(data, request); err != nil {
+			return false
+		}
+		return bytes.Equal(request.PublicKey, peer.PublicKey) && request.NumMsgs == peer.NumMsgs &&
+			equalInputs(request.Inputs, peer.Inputs)
+	case messages.C_EXP_DC_VECTOR:
+		request := &messages.DCExpRequest{}
+		if err := proto.Unmarshal(data, request); err != nil {
+			return false
+		}
+		return equalVectors(request.DCExpVector, peer.DCVector)
+	case messages.C_SIMPLE_DC_VECTOR:
+		request := &messages.DCSimpleRequest{}
+		if err := proto.Unmarshal(data, request); err != nil {
+			return false
+		}
+		return equalSlots(request.DCSimpleVector, peer.DCSimpleVector) &&
+			request.MyOk == peer.OK &&
+			bytes.Equal(request.NextPublicKey, peer.NextPublicKey)
+	case messages.C_TX_CONFIRMATION:
+		request := &messages.ConfirmationRequest{}
+		if err := proto.Unmarshal(data, request); err != nil {
+			return false
+		}
+		return request.Confirmation == peer.Confirmation && equalSignatures(request.Signatures, peer.Signatures)
+	case messages.C_EXP_DC_COMMIT, messages.C_SIMPLE_DC_COMMIT:
+		request := &messages.CommitRequest{}
+		if err := proto.Unmarshal(data, request); err != nil {
+			return false
+		}
+		return bytes.Equal(request.Commitment, peer.Commitment)
+	case messages.C_KESK_RESPONSE:
+		request := &messages.InitiaiteKESKResponse{}
+		if err := proto.Unmarshal(data, request); err != nil {
+			return false
+		}
+		return bytes.Equal(request.PrivateKey, peer.PrivateKey)
+	}
+
+	return false
+}
+
+// checks if two DC-EXP vectors are equal
+func equalVectors(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// checks if inputs announced by peer are equal
+func equalInputs(a, b []*messages.Input) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// checks if signatures of inputs are equal
+func equalSignatures(a, b []*messages.InputSignature) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// checks if two DC-SIMPLE vectors are equal
+func equalSlots(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,297 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-client/dc"
+	"github.com/dev-appmonsters/dicemix-light-client/ecdsa"
+	"github.com/dev-appmonsters/dicemix-light-client/field"
+	"github.com/dev-appmonsters/dicemix-light-client/messages"
+	"github.com/dev-appmonsters/dicemix-light-client/utils"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type poolTest struct {
+	response *messages.DiceMixResponse
+	honoured bool
+}
+
+func startResponse(denomination uint64, version uint32, numMsgs ...uint32) *messages.DiceMixResponse {
+	response := &messages.DiceMixResponse{
+		Header:       &messages.ResponseHeader{Code: messages.S_START_DICEMIX},
+		Denomination: denomination,
+		Version:      version,
+		SlotSize:     dc.DefaultSlotSize,
+	}
+	for i, n := range numMsgs {
+		response.Peers = append(response.Peers, &messages.PeersInfo{Id: int32(i + 1), NumMsgs: n})
+	}
+	return response
+}
+
+var poolTests = []poolTest{
+	{startResponse(100000, utils.ProtocolVersion, 2, 1, 3), true},
+	{startResponse(100000, utils.ProtocolVersion, 2, 1, 3, 1), true},
+	// other denomination
+	{startResponse(50000, utils.ProtocolVersion, 2, 1, 3), false},
+	// other protocol version
+	{startResponse(100000, utils.ProtocolVersion+1, 2, 1, 3), false},
+	// other slot size
+	{&messages.DiceMixResponse{
+		Header:       &messages.ResponseHeader{Code: messages.S_START_DICEMIX},
+		Denomination: 100000,
+		Version:      utils.ProtocolVersion,
+		SlotSize:     dc.DefaultSlotSize + 8,
+		Peers:        []*messages.PeersInfo{{Id: 1, NumMsgs: 2}, {Id: 2, NumMsgs: 1}, {Id: 3, NumMsgs: 1}},
+	}, false},
+	// anonymity set too small
+	{startResponse(100000, utils.ProtocolVersion, 2, 1), false},
+	// placed with other number of messages
+	{startResponse(100000, utils.ProtocolVersion, 1, 1, 3), false},
+	// run commits to DC vectors, we did'nt request it
+	{&messages.DiceMixResponse{
+		Header:       &messages.ResponseHeader{Code: messages.S_START_DICEMIX},
+		Denomination: 100000,
+		Version:      utils.ProtocolVersion,
+		SlotSize:     dc.DefaultSlotSize,
+		Commit:       true,
+		Peers:        []*messages.PeersInfo{{Id: 1, NumMsgs: 2}, {Id: 2, NumMsgs: 1}, {Id: 3, NumMsgs: 1}},
+	}, false},
+	// run does'nt include us
+	{&messages.DiceMixResponse{
+		Header:       &messages.ResponseHeader{Code: messages.S_START_DICEMIX},
+		Denomination: 100000,
+		Version:      utils.ProtocolVersion,
+		SlotSize:     dc.DefaultSlotSize,
+		Peers:        []*messages.PeersInfo{{Id: 4, NumMsgs: 2}, {Id: 5, NumMsgs: 1}, {Id: 6, NumMsgs: 1}},
+	}, false},
+}
+
+func TestVerifyPool(t *testing.T) {
+	state := &utils.State{
+		Pool:       utils.Pool{Denomination: 100000, MinPeers: 3, SlotSize: dc.DefaultSlotSize},
+		MyMsgCount: 2,
+	}
+	state.Session.MyID = 1
+
+	for _, test := range poolTests {
+		err := verifyPool(state, test.response)
+		if test.honoured && err != nil || !test.honoured && !errors.Is(err, utils.ErrProtocolViolation) {
+			t.Error(
+				"For", test.response,
+				"expected", test.honoured,
+				"got", err,
+			)
+		}
+	}
+}
+
+func TestMatchInputs(t *testing.T) {
+	input := &messages.Input{TxHash: make([]byte, 32), Index: 1, Value: 100000}
+	request, _ := proto.Marshal(&messages.KeyExchangeRequest{
+		Header:    requestHeader(messages.C_KEY_EXCHANGE, 1, 2, 1),
+		PublicKey: []byte{1},
+		NumMsgs:   1,
+		Inputs:    []*messages.Input{input},
+	})
+
+	tests := []struct {
+		inputs  []*messages.Input
+		matches bool
+	}{
+		{[]*messages.Input{input}, true},
+		// server dropped input
+		{nil, false},
+		// server relayed other outpoint
+		{[]*messages.Input{{TxHash: make([]byte, 32), Index: 2, Value: 100000}}, false},
+	}
+
+	for i, test := range tests {
+		peer := &messages.PeersInfo{Id: 2, PublicKey: []byte{1}, NumMsgs: 1, Inputs: test.inputs}
+		if matchPeerInfo(messages.C_KEY_EXCHANGE, request, peer) != test.matches {
+			t.Error("For", i, "expected", test.matches, "got", !test.matches)
+		}
+	}
+}
+
+func TestMatchSignatures(t *testing.T) {
+	signature := &messages.InputSignature{TxHash: make([]byte, 32), Witness: [][]byte{{1}, {2}}}
+	request, _ := proto.Marshal(&messages.ConfirmationRequest{
+		Header:       requestHeader(messages.C_TX_CONFIRMATION, 1, 2, 1),
+		Confirmation: true,
+		Signatures:   []*messages.InputSignature{signature},
+	})
+
+	tests := []struct {
+		peer    *messages.PeersInfo
+		matches bool
+	}{
+		{&messages.PeersInfo{Id: 2, Confirmation: true, Signatures: []*messages.InputSignature{signature}}, true},
+		// server dropped signature
+		{&messages.PeersInfo{Id: 2, Confirmation: true}, false},
+		// server relayed other witness
+		{&messages.PeersInfo{Id: 2, Confirmation: true, Signatures: []*messages.InputSignature{{TxHash: make([]byte, 32), Witness: [][]byte{{1}}}}}, false},
+		// server relayed rejection as confirmation
+		{&messages.PeersInfo{Id: 2, Signatures: []*messages.InputSignature{signature}}, false},
+	}
+
+	for i, test := range tests {
+		if matchPeerInfo(messages.C_TX_CONFIRMATION, request, test.peer) != test.matches {
+			t.Error("For", i, "expected", test.matches, "got", !test.matches)
+		}
+	}
+}
+
+func TestVerifyCommitments(t *testing.T) {
+	state := &utils.State{Pool: utils.Pool{Commit: true}, Run: 2}
+	state.Session.SessionID = 7
+
+	vectors := [][]uint64{{1, 2}, {3, 4}, {5, 6}}
+	for i, vector := range vectors {
+		id := int32(i + 2)
+		state.Peers = append(state.Peers, utils.Peers{
+			ID:              id,
+			DCVector:        vector,
+			DCExpCommitment: dc.CommitDCExp(7, 2, id, vector),
+		})
+	}
+
+	if err := verifyCommitments(state, messages.C_EXP_DC_VECTOR); err != nil {
+		t.Error("For", "revealed vectors", "expected", nil, "got", err)
+	}
+
+	// peer 3 revealed other vector, peer 4 copied commitment of peer 2
+	state.Peers[1].DCVector = []uint64{3, 5}
+	state.Peers[2].DCVector, state.Peers[2].DCExpCommitment = vectors[0], state.Peers[0].DCExpCommitment
+
+	var e *utils.Error
+	err := verifyCommitments(state, messages.C_EXP_DC_VECTOR)
+	if !errors.As(err, &e) || !errors.Is(err, utils.ErrPeerMisbehaviour) || len(e.Peers) != 2 || e.Peers[0] != 3 || e.Peers[1] != 4 {
+		t.Error("For", "mismatched vectors", "expected", []int32{3, 4}, "got", err)
+	}
+
+	// runs without commitments are'nt verified
+	state.Pool.Commit = false
+	if err := verifyCommitments(state, messages.C_EXP_DC_VECTOR); err != nil {
+		t.Error("For", "run without commitments", "expected", nil, "got", err)
+	}
+}
+
+func TestVerifyRoots(t *testing.T) {
+	hashes := []uint64{11, 7, 23}
+
+	// power sums of all messages, randomness of peers cancelled out
+	state := &utils.State{MyMsgCount: 1, MyDC: make([]uint64, len(hashes))}
+	for _, hash := range hashes {
+		pow := field.NewField(1)
+		for i := range state.MyDC {
+			pow = pow.Mul(field.NewField(hash))
+			state.MyDC[i] = field.NewField(state.MyDC[i]).Add(pow).Value()
+		}
+	}
+	state.Peers = []utils.Peers{
+		{ID: 2, NumMsgs: 1, DCVector: make([]uint64, len(hashes))},
+		{ID: 3, NumMsgs: 1, DCVector: make([]uint64, len(hashes))},
+	}
+
+	roots, err := dc.NewDCNetwork().ResolveDCExp(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		serverRoots []uint64
+		valid       bool
+	}{
+		{[]uint64{7, 11, 23}, true},
+		// order of roots sent by server is'nt used
+		{[]uint64{11, 7, 23}, true},
+		{[]uint64{7, 23, 11}, true},
+		{[]uint64{7, 11, 29}, false},
+		{[]uint64{7, 11, 11}, false},
+		{[]uint64{7, 11}, false},
+		{[]uint64{7, 11, 23, 29}, false},
+	}
+
+	for _, test := range tests {
+		err := verifyRoots(roots, test.serverRoots)
+		if (err == nil) != test.valid || (err != nil && !errors.Is(err, utils.ErrProtocolViolation)) {
+			t.Error("For", test.serverRoots, "expected", test.valid, "got", err)
+		}
+	}
+}
+
+func TestVerifyReplayedRequest(t *testing.T) {
+	ltpk, ltsk, _ := ecdsa.NewCurveECDSA().GenerateKeyPair()
+	state := &utils.State{Run: 2, Peers: []utils.Peers{{ID: 2, LTPubKey: ltpk}}}
+	state.Session.SessionID = 1
+
+	// DC-EXP vector and KESK signed by peer 2 in run
+	signed := func(run uint32) *messages.PeersInfo {
+		dcExp, _ := proto.Marshal(&messages.DCExpRequest{
+			Header:      requestHeader(messages.C_EXP_DC_VECTOR, 1, 2, run),
+			DCExpVector: []uint64{1, 2},
+		})
+		kesk, _ := proto.Marshal(&messages.InitiaiteKESKResponse{
+			Header:     requestHeader(messages.C_KESK_RESPONSE, 1, 2, run),
+			PrivateKey: []byte{byte(run)},
+		})
+		signedDCExp, _ := generateSignedRequest(ltsk, dcExp)
+		signedKESK, _ := generateSignedRequest(ltsk, kesk)
+
+		return &messages.PeersInfo{
+			Id:             2,
+			DCVector:       []uint64{1, 2},
+			PrivateKey:     []byte{byte(run)},
+			SignedRequests: [][]byte{signedDCExp, signedKESK},
+		}
+	}
+
+	if err := verifyPeersInfo(state, []*messages.PeersInfo{signed(2)}); err != nil {
+		t.Error("For", "run", 2, "expected", nil, "got", err)
+	}
+
+	// requests of previous run replayed by server should'nt blame peer
+	for _, run := range []uint32{1, 3} {
+		err := verifyPeersInfo(state, []*messages.PeersInfo{signed(run)})
+		if !errors.Is(err, utils.ErrProtocolViolation) || errors.Is(err, utils.ErrPeerMisbehaviour) {
+			t.Error("For", "run", run, "expected", utils.ErrProtocolViolation, "got", err)
+		}
+	}
+}
+
+func TestVerifyRelayed(t *testing.T) {
+	state := &utils.State{Peers: []utils.Peers{{ID: 2}, {ID: 3}, {ID: 4}}}
+	state.Session.MyID = 1
+
+	tests := []struct {
+		ids   []int32
+		valid bool
+	}{
+		{[]int32{1, 2, 3, 4}, true},
+		{[]int32{2, 3, 4}, true},
+		// server left out peer colluding with it
+		{[]int32{1, 2, 4}, false},
+		{[]int32{1, 2, 3, 3}, false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		peers := make([]*messages.PeersInfo, len(test.ids))
+		for i, id := range test.ids {
+			peers[i] = &messages.PeersInfo{Id: id}
+		}
+
+		err := verifyPeersInfo(state, peers)
+		if (err == nil) != test.valid || (err != nil && !errors.Is(err, utils.ErrProtocolViolation)) {
+			t.Error("For", test.ids, "expected", test.valid, "got", err)
+		}
+
+		// peers are never dropped on word of server
+		if err := filterPeers(state, peers); (err == nil) != test.valid || len(state.Peers) != 3 {
+			t.Error("For", test.ids, "expected", 3, "got", len(state.Peers), err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package solver
+
+import (
+	"github.com/dev-appmonsters/dicemix-light-client/field"
+)
+
+// polynomial over field, coefficients are stored
+// from lowest to highest degree i.e. p[i] is coefficient of x^i
+type polynomial []field.Field
+
+// degree of polynomial, -1 for zero polynomial
+func (p polynomial) degree() int {
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i].Value() != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// removes leading zero coefficients
+func (p polynomial) trim() polynomial {
+	return p[:p.degree()+1]
+}
+
+// leading coefficient of polynomial
+func (p polynomial) lead() field.Field {
+	return p[p.degree()]
+}
+
+// returns monic polynomial (leading coefficient 1)
+func (p polynomial) monic() polynomial {
+	p = p.trim()
+	if len(p) == 0 {
+		return p
+	}
+	inv := p.lead().Inv()
+	res := make(polynomial, len(p))
+	for i := range p {
+		res[i] = p[i].Mul(inv)
+	}
+	return res
+}
+
+// p (-) q
+func (p polynomial) sub(q polynomial) polynomial {
+	n := len(p)
+	if len(q) > n {
+		n = len(q)
+	}
+	res := make(polynomial, n)
+	for i := range res {
+		if i < len(p) {
+			res[i] = p[i]
+		}
+		if i < len(q) {
+			res[i] = res[i].Sub(q[i])
+		}
+	}
+	return res.trim()
+}
+
+// p (*) q
+func (p polynomial) mul(q polynomial) polynomial {
+	p, q = p.trim(), q.trim()
+	if len(p) == 0 || len(q) == 0 {
+		return polynomial{}
+	}
+	res := make(polynomial, len(p)+len(q)-1)
+	for i := range p {
+		if p[i].Value() == 0 {
+			continue
+		}
+		for j := range q {
+			res[i+j] = res[i+j].Add(p[i].Mul(q[j]))
+		}
+	}
+	return res.trim()
+}
+
+// returns quotient and remainder of p (/) q
+// q should be non zero polynomial
+func (p polynomial) divMod(q polynomial) (polynomial, polynomial) {
+	q = q.trim()
+	rem := append(polynomial{}, p.trim()...)
+	if len(rem) < len(q) {
+		return polynomial{}, rem
+	}
+
+	inv := q.lead().Inv()
+	quo := make(polynomial, len(rem)-len(q)+1)
+	for i := len(rem) - len(q); i >= 0; i-- {
+		coeff := rem[i+len(q)-1].Mul(inv)
+		quo[i] = coeff
+		if coeff.Value() == 0 {
+			continue
+		}
+		for j := range q {
+			rem[i+j] = rem[i+j].Sub(coeff.Mul(q[j]))
+		}
+	}
+	return quo.trim(), rem.trim()
+}
+
+// p mod q
+func (p polynomial) mod(q polynomial) polynomial {
+	_, rem := p.divMod(q)
+	return rem
+}
+
+// computes p ** exp mod m using square and multiply
+func (p polynomial) powMod(exp uint64, m polynomial) polynomial {
+	res := polynomial{field.NewField(1)}.mod(m)
+	base := p.mod(m)
+	for exp > 0 {
+		if exp&1 == 1 {
+			res = res.mul(base).mod(m)
+		}
+		base = base.mul(base).mod(m)
+		exp >>= 1
+	}
+	return res
+}
+
+// returns monic greatest common divisor of p and q
+func gcd(p, q polynomial) polynomial {
+	p, q = p.trim(), q.trim()
+	for len(q) != 0 {
+		p, q = q, p.mod(q)
+	}
+	return p.monic()
+}
@@ -0,0 +1,99 @@
+package solver
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+
+	"github.com/dev-appmonsters/dicemix-light-client/field"
+)
+
+// ErrNoSolution - power sums does'nt correspond to
+// a set of distinct roots in the field (e.g. colliding message hashes)
+var ErrNoSolution = errors.New("power sums have no solution in field")
+
+// x - polynomial
+var x = polynomial{field.NewField(0), field.NewField(1)}
+
+// Solve - obtains message hashes from their power sums
+// sums[i] := ∑(hashes[j] ** (i + 1))
+// converts power sums into polynomial with hashes as its roots
+// using Newton's identities and finds its roots using Cantor–Zassenhaus
+// returns roots sorted in ascending order
+func Solve(sums []uint64) ([]uint64, error) {
+	if len(sums) == 0 {
+		return []uint64{}, nil
+	}
+
+	f := newtonIdentities(sums)
+
+	// f should split into distinct linear factors over field
+	// i.e. f divides x^P - x
+	g := gcd(f, x.powMod(field.P.Value(), f).sub(x))
+	if g.degree() != f.degree() {
+		return nil, ErrNoSolution
+	}
+
+	roots := make([]uint64, 0, len(sums))
+	for _, root := range findRoots(f) {
+		roots = append(roots, root.Value())
+	}
+
+	sort.Slice(roots, func(i, j int) bool { return roots[i] < roots[j] })
+
+	return roots, nil
+}
+
+// converts power sums to monic polynomial using Newton's identities
+// k * e[k] := ∑ (-1)^(i-1) * e[k-i] * sums[i-1], 1 <= i <= k
+// f(x) := ∑ (-1)^k * e[k] * x^(n-k)
+func newtonIdentities(sums []uint64) polynomial {
+	n := len(sums)
+	e := make([]field.Field, n+1)
+	e[0] = field.NewField(1)
+
+	for k := 1; k <= n; k++ {
+		var sum field.Field
+		for i := 1; i <= k; i++ {
+			term := e[k-i].Mul(field.NewField(sums[i-1]))
+			if i%2 == 0 {
+				term = term.Neg()
+			}
+			sum = sum.Add(term)
+		}
+		e[k] = sum.Mul(field.NewField(uint64(k)).Inv())
+	}
+
+	f := make(polynomial, n+1)
+	for k := 0; k <= n; k++ {
+		f[n-k] = e[k]
+		if k%2 == 1 {
+			f[n-k] = f[n-k].Neg()
+		}
+	}
+	return f
+}
+
+// finds roots of monic polynomial f which is product of distinct linear factors
+// splits f using Cantor–Zassenhaus equal degree factorization
+func findRoots(f polynomial) []field.Field {
+	switch f.degree() {
+	case 0:
+		return []field.Field{}
+	case 1:
+		// f := x + c, root := -c
+		return []field.Field{f[0].Neg()}
+	}
+
+	for {
+		// h := gcd(f, (x + a)^((P - 1) / 2) - 1) for random a
+		// splits f with probability ~1/2
+		a := polynomial{field.NewField(rand.Uint64()), field.NewField(1)}
+		h := gcd(f, a.powMod((field.P.Value()-1)/2, f).sub(polynomial{field.NewField(1)}))
+
+		if h.degree() > 0 && h.degree() < f.degree() {
+			q, _ := f.divMod(h)
+			return append(findRoots(h), findRoots(q.monic())...)
+		}
+	}
+}
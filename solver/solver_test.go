@@ -0,0 +1,90 @@
+package solver
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/dev-appmonsters/dicemix-light-client/field"
+)
+
+type testpair struct {
+	hashes []uint64
+	sums   []uint64
+}
+
+var solveTests = []testpair{
+	{[]uint64{5}, []uint64{5}},
+	{[]uint64{2, 3}, []uint64{5, 13}},
+	{[]uint64{1, 2, 3}, []uint64{6, 14, 36}},
+}
+
+// generates power sums of hashes
+func powerSums(hashes []uint64) []uint64 {
+	sums := make([]uint64, len(hashes))
+	for _, hash := range hashes {
+		pow := field.NewField(1)
+		for i := range sums {
+			pow = pow.Mul(field.NewField(hash))
+			sums[i] = field.NewField(sums[i]).Add(pow).Value()
+		}
+	}
+	return sums
+}
+
+func equal(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSolve(t *testing.T) {
+	for _, pair := range solveTests {
+		roots, err := Solve(pair.sums)
+		if err != nil || !equal(roots, pair.hashes) {
+			t.Error(
+				"For", pair.sums,
+				"expected", pair.hashes,
+				"got", roots, err,
+			)
+		}
+	}
+}
+
+func TestSolveRandom(t *testing.T) {
+	for n := 1; n <= 30; n++ {
+		hashes := make([]uint64, n)
+		for i := range hashes {
+			hashes[i] = field.NewField(rand.Uint64()).Value()
+		}
+		sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+		roots, err := Solve(powerSums(hashes))
+		if err != nil || !equal(roots, hashes) {
+			t.Error(
+				"For", hashes,
+				"expected", hashes,
+				"got", roots, err,
+			)
+		}
+	}
+}
+
+func TestSolveCollision(t *testing.T) {
+	hashes := []uint64{7, 7, 11}
+
+	roots, err := Solve(powerSums(hashes))
+	if err != ErrNoSolution {
+		t.Error(
+			"For", hashes,
+			"expected", ErrNoSolution,
+			"got", roots, err,
+		)
+	}
+}
@@ -0,0 +1,85 @@
+package transport
+
+import (
+	"sync"
+)
+
+// unbounded queue of frames
+// push never blocks, so peers can send without waiting for each other
+type queue struct {
+	sync.Mutex
+	cond   *sync.Cond
+	frames [][]byte
+	closed bool
+}
+
+func newQueue() *queue {
+	q := &queue{}
+	q.cond = sync.NewCond(&q.Mutex)
+	return q
+}
+
+func (q *queue) push(frame []byte) error {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+	q.frames = append(q.frames, frame)
+	q.cond.Signal()
+	return nil
+}
+
+// blocks until frame is available or queue is closed
+func (q *queue) pop() ([]byte, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	for len(q.frames) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.frames) == 0 {
+		return nil, ErrClosed
+	}
+
+	frame := q.frames[0]
+	q.frames = q.frames[1:]
+	return frame, nil
+}
+
+func (q *queue) close() {
+	q.Lock()
+	defer q.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// one end of in-memory transport
+type pipeEnd struct {
+	in  *queue
+	out *queue
+}
+
+// Pipe creates in-memory transport
+// frames sent on one end are received on other
+func Pipe() (Transport, Transport) {
+	a, b := newQueue(), newQueue()
+	return &pipeEnd{in: a, out: b}, &pipeEnd{in: b, out: a}
+}
+
+func (p *pipeEnd) Send(frame []byte) error {
+	return p.out.push(frame)
+}
+
+func (p *pipeEnd) Receive() ([]byte, error) {
+	return p.in.pop()
+}
+
+// closes both directions of transport
+func (p *pipeEnd) Close() error {
+	p.in.close()
+	p.out.close()
+	return nil
+}
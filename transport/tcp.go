@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// MaxFrameSize - Basic sanity check to avoid weird inputs
+// frames larger than MaxFrameSize bytes are rejected
+const MaxFrameSize = 16 << 20
+
+// every frame is prefixed with its length
+// as 4 byte big endian integer
+type tcpTransport struct {
+	conn net.Conn
+	// serializes writes of length and frame
+	sync.Mutex
+}
+
+// NewTCP creates a Transport over established stream connection
+func NewTCP(conn net.Conn) Transport {
+	return &tcpTransport{conn: conn}
+}
+
+// DialTCP connects to server at addr (host:port)
+// connection is secured via TLS if config is provided
+func DialTCP(ctx context.Context, addr string, config *tls.Config) (Transport, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if config != nil {
+		tlsConn := tls.Client(conn, config)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	return NewTCP(conn), nil
+}
+
+func (t *tcpTransport) Send(frame []byte) error {
+	if len(frame) > MaxFrameSize {
+		return fmt.Errorf("frame of %d bytes exceeds limit", len(frame))
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	var prefix [4]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(len(frame)))
+	if _, err := t.conn.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err := t.conn.Write(frame)
+	return err
+}
+
+func (t *tcpTransport) Receive() ([]byte, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(t.conn, prefix[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size > MaxFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes exceeds limit", size)
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(t.conn, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (t *tcpTransport) Close() error {
+	return t.conn.Close()
+}
@@ -0,0 +1,52 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrClosed - transport has been closed
+var ErrClosed = errors.New("transport closed")
+
+// Transport - The main interface to exchange frames with peer.
+// frame is a marshalled proto, transport preserves its boundaries
+// Close can be called concurrently with Send and Receive
+// to unblock them
+type Transport interface {
+	Send(frame []byte) error
+	Receive() ([]byte, error)
+	Close() error
+}
+
+// Dialer - establishes transport with server
+type Dialer func(ctx context.Context) (Transport, error)
+
+// ErrDeadline - frame was'nt received before deadline
+var ErrDeadline = errors.New("deadline exceeded")
+
+// ReceiveDeadline - receives frame from t waiting until deadline
+// t is closed once deadline passes, so it can't be used further
+func ReceiveDeadline(t Transport, deadline time.Time) ([]byte, error) {
+	type result struct {
+		frame []byte
+		err   error
+	}
+
+	received := make(chan result, 1)
+	go func() {
+		frame, err := t.Receive()
+		received <- result{frame, err}
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case r := <-received:
+		return r.frame, r.err
+	case <-timer.C:
+		t.Close()
+		return nil, ErrDeadline
+	}
+}
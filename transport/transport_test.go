@@ -0,0 +1,163 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// returns connected ends of websocket transport
+func websocketPair(t *testing.T) (Transport, Transport, func()) {
+	accepted := make(chan Transport, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		accepted <- NewWebsocket(conn)
+	}))
+
+	client, err := DialWebsocket(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, <-accepted, srv.Close
+}
+
+// returns connected ends of length-prefixed TCP transport
+func tcpPair(t *testing.T) (Transport, Transport, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan Transport, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- NewTCP(conn)
+		}
+	}()
+
+	client, err := DialTCP(context.Background(), listener.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, <-accepted, func() { listener.Close() }
+}
+
+func pipePair(t *testing.T) (Transport, Transport, func()) {
+	client, server := Pipe()
+	return client, server, func() {}
+}
+
+var pairs = map[string]func(*testing.T) (Transport, Transport, func()){
+	"websocket": websocketPair,
+	"tcp":       tcpPair,
+	"pipe":      pipePair,
+}
+
+func TestRoundTrip(t *testing.T) {
+	frames := [][]byte{[]byte("frame"), {}, bytes.Repeat([]byte{0xab}, 1<<16)}
+
+	for name, pair := range pairs {
+		client, server, stop := pair(t)
+
+		for _, frame := range frames {
+			if err := client.Send(frame); err != nil {
+				t.Fatal("For", name, "expected", nil, "got", err)
+			}
+		}
+
+		// frame boundaries are preserved
+		for _, frame := range frames {
+			got, err := server.Receive()
+			if err != nil || !bytes.Equal(got, frame) {
+				t.Error("For", name, "expected", len(frame), "got", len(got), err)
+			}
+		}
+
+		if err := server.Send(frames[0]); err != nil {
+			t.Fatal("For", name, "expected", nil, "got", err)
+		}
+		if got, err := client.Receive(); err != nil || !bytes.Equal(got, frames[0]) {
+			t.Error("For", name, "expected", frames[0], "got", got, err)
+		}
+
+		client.Close()
+		server.Close()
+		stop()
+	}
+}
+
+func TestCloseUnblocksReceive(t *testing.T) {
+	for name, pair := range pairs {
+		client, server, stop := pair(t)
+
+		received := make(chan error, 1)
+		go func() {
+			_, err := client.Receive()
+			received <- err
+		}()
+
+		client.Close()
+		select {
+		case err := <-received:
+			if err == nil {
+				t.Error("For", name, "expected", "error", "got", nil)
+			}
+		case <-time.After(time.Second):
+			t.Error("For", name, "expected", "receive unblocked", "got", "blocked")
+		}
+
+		server.Close()
+		stop()
+	}
+}
+
+func TestReceiveDeadline(t *testing.T) {
+	client, server := Pipe()
+	defer server.Close()
+
+	_, err := ReceiveDeadline(client, time.Now().Add(50*time.Millisecond))
+	if !errors.Is(err, ErrDeadline) {
+		t.Error("For", "silent peer", "expected", ErrDeadline, "got", err)
+	}
+
+	// transport is closed after deadline
+	if err = client.Send([]byte("frame")); !errors.Is(err, ErrClosed) {
+		t.Error("For", "send after deadline", "expected", ErrClosed, "got", err)
+	}
+
+	client, server = Pipe()
+	server.Send([]byte("frame"))
+	if frame, err := ReceiveDeadline(client, time.Now().Add(time.Second)); err != nil || string(frame) != "frame" {
+		t.Error("For", "delivered frame", "expected", "frame", "got", frame, err)
+	}
+}
+
+func TestTCPFrameLimit(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// peer announces frame larger than limit
+	go func() {
+		var prefix [4]byte
+		binary.BigEndian.PutUint32(prefix[:], MaxFrameSize+1)
+		client.Write(prefix[:])
+	}()
+
+	if _, err := NewTCP(server).Receive(); err == nil {
+		t.Error("For", "oversized frame", "expected", "error", "got", nil)
+	}
+}
@@ -0,0 +1,46 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// frames are exchanged as binary websocket messages
+type wsTransport struct {
+	conn *websocket.Conn
+}
+
+// NewWebsocket creates a Transport over established websocket connection
+func NewWebsocket(conn *websocket.Conn) Transport {
+	return &wsTransport{conn}
+}
+
+// DialWebsocket connects to websocket server at url
+// config is used for wss:// urls
+func DialWebsocket(ctx context.Context, url string, config *tls.Config) (Transport, error) {
+	dialer := websocket.Dialer{TLSClientConfig: config}
+	conn, _, err := dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewWebsocket(conn), nil
+}
+
+func (w *wsTransport) Send(frame []byte) error {
+	return w.conn.WriteMessage(websocket.BinaryMessage, frame)
+}
+
+func (w *wsTransport) Receive() ([]byte, error) {
+	_, frame, err := w.conn.ReadMessage()
+	return frame, err
+}
+
+// sends close message before closing connection
+func (w *wsTransport) Close() error {
+	w.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	return w.conn.Close()
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors - kinds of failures which can occur during DiceMix run
+// use errors.Is to check kind of error returned
+var (
+	// ErrTimeout - server did'nt respond within time
+	ErrTimeout = errors.New("timeout")
+
+	// ErrProtocolViolation - server sent message which violates DiceMix protocol
+	ErrProtocolViolation = errors.New("protocol violation")
+
+	// ErrPeerMisbehaviour - peer sent invalid or inconsistent data
+	ErrPeerMisbehaviour = errors.New("peer misbehaviour")
+
+	// ErrCrypto - cryptographic operation failed
+	ErrCrypto = errors.New("crypto failure")
+
+	// ErrServer - server reported error in response header
+	ErrServer = errors.New("server error")
+
+	// ErrPinMismatch - server certificate does'nt match pinned keys
+	ErrPinMismatch = errors.New("certificate pin mismatch")
+)
+
+// Error - describes failure of DiceMix run
+type Error struct {
+	// Kind - one of sentinel errors
+	Kind error
+	// Code - code of message being handled, 0 if none
+	Code uint32
+	// Peers - Id's of peers responsible for failure, if any
+	Peers []int32
+	// Err - underlying cause of failure
+	Err error
+}
+
+// NewError creates Error of given kind
+func NewError(kind error, format string, args ...interface{}) *Error {
+	return &Error{
+		Kind: kind,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+// NewPeerError creates Error of kind ErrPeerMisbehaviour
+// blaming peers provided
+func NewPeerError(peers []int32, format string, args ...interface{}) *Error {
+	return &Error{
+		Kind:  ErrPeerMisbehaviour,
+		Peers: peers,
+		Err:   fmt.Errorf(format, args...),
+	}
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.Error()
+	if e.Code != 0 {
+		msg += fmt.Sprintf(" (code %d)", e.Code)
+	}
+	if len(e.Peers) != 0 {
+		msg += fmt.Sprintf(" by peers %v", e.Peers)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns underlying cause of failure
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether error is of kind target
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorKind(t *testing.T) {
+	var kinds = []error{ErrTimeout, ErrProtocolViolation, ErrPeerMisbehaviour, ErrCrypto, ErrServer}
+
+	for _, kind := range kinds {
+		// kind should be preserved even if error is wrapped further
+		err := fmt.Errorf("run failed: %w", NewError(kind, "cause"))
+
+		for _, target := range kinds {
+			if errors.Is(err, target) != (kind == target) {
+				t.Error(
+					"For", err,
+					"expected", kind == target,
+					"got", errors.Is(err, target),
+				)
+			}
+		}
+	}
+}
+
+func TestPeerError(t *testing.T) {
+	var cause = errors.New("cause")
+	var err error = NewPeerError([]int32{3, 7}, "invalid vector: %w", cause)
+
+	var e *Error
+	if !errors.As(err, &e) || len(e.Peers) != 2 || e.Peers[0] != 3 || e.Peers[1] != 7 {
+		t.Error(
+			"For", err,
+			"expected", []int32{3, 7},
+			"got", e,
+		)
+	}
+
+	if !errors.Is(err, ErrPeerMisbehaviour) || !errors.Is(err, cause) {
+		t.Error(
+			"For", err,
+			"expected", "peer misbehaviour caused by ", cause,
+			"got", err,
+		)
+	}
+}
@@ -15,21 +15,69 @@ const (
 	// if messages are more than MaxAllowedMessages then close connection
 	MaxAllowedMessages = 1000
 
-	// ResponseWait - Time to wait for response from server
+	// ResponseWait - Default time to wait for response from server in every phase
 	// if server does'nt response within ResponseWait seconds then close connection
 	ResponseWait = 30
+
+	// ProtocolVersion - version of DiceMix protocol implemented by client
+	ProtocolVersion = 1
 )
 
 // Peers - Stores all Peers Info
 type Peers struct {
 	ID             int32
+	LTPubKey       []byte
 	PubKey         []byte
+	NextPubKey     []byte
+	Kesk           []byte
 	NumMsgs        uint32
 	SharedKey      []byte
 	Dicemix        rng.DiceMixRng
+	DCVector       []uint64
 	DCSimpleVector [][]byte
 	Ok             bool
 	Confirmation   bool
+	Inputs         []Input
+	Signatures     []Signature
+	// commitments to DC vectors received before they are revealed
+	DCExpCommitment    []byte
+	DCSimpleCommitment []byte
+}
+
+// Input - unspent output spent in coinjoin transaction
+type Input struct {
+	// TxHash - hash of previous transaction in internal byte order
+	TxHash []byte
+	// Index - index of output in previous transaction
+	Index uint32
+	// Value - amount of output in satoshis
+	Value uint64
+	// PkScript - script of output, required to sign input
+	PkScript []byte
+	// PrivateKey - secp256k1 key signing our input, never sent to peers
+	PrivateKey []byte
+}
+
+// Signature - signature of input spending output TxHash:Index
+type Signature struct {
+	TxHash []byte
+	Index  uint32
+	// SignatureScript - set for P2PKH inputs
+	SignatureScript []byte
+	// Witness - set for P2WPKH inputs
+	Witness [][]byte
+}
+
+// Pool - parameters of pool of DiceMix runs we want to join
+type Pool struct {
+	// Denomination - amount every output of run is worth
+	Denomination uint64
+	// MinPeers - minimum anonymity set including us
+	MinPeers uint32
+	// SlotSize - size of DC-SIMPLE slots in bytes
+	SlotSize uint32
+	// Commit - every DC vector is committed to before it is revealed
+	Commit bool
 }
 
 // Session stores information of current Session
@@ -45,10 +93,11 @@ type session struct {
 }
 
 // State - stores state info for current run
-// TODO: remove ltsk and ltpk from state and
-// store them in more persistent storage
+// ltsk persisted across sessions is stored in keystore
 type State struct {
+	Pool           Pool
 	Session        session
+	Run            uint32
 	Peers          []Peers
 	AllMsgHashes   []uint64
 	MyDC           []uint64
@@ -57,16 +106,20 @@ type State struct {
 	MyMessagesHash []uint64
 	MyMsgCount     uint32
 	DCSimpleVector [][]byte
+	DCSimpleSent   bool
+	MyExposed      bool
 	AllMessages    [][]byte
+	MaliciousPeers []int32
+	MyInputs       []Input
+	Transaction    []byte
 }
 
-// GenerateMessage - generates a random 20 byte string (160 bits)
-// returns string encoded with Base58 format
-func GenerateMessage() string {
+// GenerateMessage - generates a random message of size bytes
+func GenerateMessage(size int) []byte {
 	rand.Seed(time.Now().UnixNano())
-	token := make([]byte, 20)
+	token := make([]byte, size)
 	rand.Read(token)
-	return BytesToBase58String(token)
+	return token
 }
 
 // BytesToBase58String - converts []byte to Base58 Encoded string